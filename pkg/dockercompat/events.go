@@ -0,0 +1,104 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockercompat
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"k8s.io/kubernetes/pkg/api"
+	"k8s.io/kubernetes/pkg/watch"
+)
+
+// dockerEvent mirrors a line of Docker's GET /events response: one JSON
+// object per line, newline-delimited, streamed as the events occur.
+type dockerEvent struct {
+	Type   string           `json:"Type"`
+	Action string           `json:"Action"`
+	Actor  dockerEventActor `json:"Actor"`
+	Time   int64            `json:"time"`
+}
+
+type dockerEventActor struct {
+	ID         string            `json:"ID"`
+	Attributes map[string]string `json:"Attributes"`
+}
+
+// events streams Pod watch events from a cluster-wide Pod watch as Docker-shaped event
+// JSON lines, so a Docker client's "docker events" can tail Pod lifecycle
+// transitions the same way it would tail container lifecycle transitions
+// against a real daemon.
+func (h *Handler) events(w http.ResponseWriter, r *http.Request) {
+	watcher, err := h.Client.Pods(api.NamespaceAll).Watch(api.ListOptions{})
+	if err != nil {
+		httpError(w, err)
+		return
+	}
+	defer watcher.Stop()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	flusher, canFlush := w.(http.Flusher)
+
+	enc := json.NewEncoder(w)
+	for event := range watcher.ResultChan() {
+		de, err := podWatchEventToDockerEvent(event)
+		if err != nil {
+			continue
+		}
+		if err := enc.Encode(de); err != nil {
+			return
+		}
+		if canFlush {
+			flusher.Flush()
+		}
+	}
+}
+
+func podWatchEventToDockerEvent(event watch.Event) (*dockerEvent, error) {
+	pod, ok := event.Object.(*api.Pod)
+	if !ok {
+		return nil, errNotAPod
+	}
+	action := map[watch.EventType]string{
+		watch.Added:    "create",
+		watch.Modified: "update",
+		watch.Deleted:  "destroy",
+	}[event.Type]
+	return &dockerEvent{
+		Type:   "container",
+		Action: action,
+		Actor: dockerEventActor{
+			ID: containerID(pod.Namespace, pod.Name, primaryContainerName(pod)),
+			Attributes: map[string]string{
+				"io.kubernetes.pod.name":      pod.Name,
+				"io.kubernetes.pod.namespace": pod.Namespace,
+			},
+		},
+		Time: pod.CreationTimestamp.Unix(),
+	}, nil
+}
+
+func primaryContainerName(pod *api.Pod) string {
+	if len(pod.Spec.Containers) == 0 {
+		return ""
+	}
+	return pod.Spec.Containers[0].Name
+}
+
+var errNotAPod = fmt.Errorf("dockercompat: watch event object was not a Pod")
@@ -0,0 +1,144 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockercompat
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"k8s.io/kubernetes/pkg/api"
+	client "k8s.io/kubernetes/pkg/client/unversioned"
+)
+
+// apiVersionPrefix is the Docker Engine API version this shim advertises
+// compatibility with. Docker clients negotiate down to the server's
+// advertised version, so this only needs to be at least as new as the
+// oldest feature the shim implements.
+const apiVersionPrefix = "/v1.24"
+
+// Handler serves a subset of the Docker Engine HTTP API translated onto a
+// Kubernetes client.Client.
+type Handler struct {
+	Client *client.Client
+	router *mux.Router
+}
+
+// NewHandler builds a Handler ready to be mounted as an http.Handler (e.g.
+// behind the apiserver's /proxy subresource).
+func NewHandler(c *client.Client) *Handler {
+	h := &Handler{Client: c, router: mux.NewRouter()}
+	h.router.HandleFunc(apiVersionPrefix+"/version", h.version).Methods("GET")
+	h.router.HandleFunc(apiVersionPrefix+"/containers/json", h.listContainers).Methods("GET")
+	h.router.HandleFunc(apiVersionPrefix+"/containers/{id:.*}/json", h.inspectContainer).Methods("GET")
+	h.router.HandleFunc(apiVersionPrefix+"/containers/{id:.*}/logs", h.containerLogs).Methods("GET")
+	h.router.HandleFunc(apiVersionPrefix+"/images/json", h.listImages).Methods("GET")
+	h.router.HandleFunc(apiVersionPrefix+"/events", h.events).Methods("GET")
+	return h
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.router.ServeHTTP(w, r)
+}
+
+func (h *Handler) version(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, map[string]string{
+		"Version":    "dockercompat-shim",
+		"ApiVersion": strings.TrimPrefix(apiVersionPrefix, "/v"),
+		"Os":         "linux",
+	})
+}
+
+func (h *Handler) listContainers(w http.ResponseWriter, r *http.Request) {
+	pods, err := h.Client.Pods(api.NamespaceAll).List(api.ListOptions{})
+	if err != nil {
+		httpError(w, err)
+		return
+	}
+	items := make([]ContainerListItem, 0, len(pods.Items))
+	for i := range pods.Items {
+		items = append(items, PodToContainerListItems(&pods.Items[i])...)
+	}
+	writeJSON(w, items)
+}
+
+func (h *Handler) inspectContainer(w http.ResponseWriter, r *http.Request) {
+	namespace, podName, containerName, err := splitContainerID(mux.Vars(r)["id"])
+	if err != nil {
+		httpError(w, err)
+		return
+	}
+	pod, err := h.Client.Pods(namespace).Get(podName)
+	if err != nil {
+		httpError(w, err)
+		return
+	}
+	result, err := PodToContainerJSON(pod, containerName)
+	if err != nil {
+		httpError(w, err)
+		return
+	}
+	writeJSON(w, result)
+}
+
+func (h *Handler) listImages(w http.ResponseWriter, r *http.Request) {
+	pods, err := h.Client.Pods(api.NamespaceAll).List(api.ListOptions{})
+	if err != nil {
+		httpError(w, err)
+		return
+	}
+	seen := map[string]bool{}
+	var images []map[string]interface{}
+	for _, pod := range pods.Items {
+		for _, c := range pod.Spec.Containers {
+			if seen[c.Image] {
+				continue
+			}
+			seen[c.Image] = true
+			images = append(images, map[string]interface{}{
+				"Id":       "sha256:" + c.Image,
+				"RepoTags": []string{c.Image},
+			})
+		}
+	}
+	writeJSON(w, images)
+}
+
+func splitContainerID(id string) (namespace, pod, container string, err error) {
+	parts := strings.SplitN(id, "/", 3)
+	if len(parts) != 3 {
+		return "", "", "", errInvalidContainerID(id)
+	}
+	return parts[0], parts[1], parts[2], nil
+}
+
+type errInvalidContainerID string
+
+func (e errInvalidContainerID) Error() string {
+	return "invalid dockercompat container id " + string(e)
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func httpError(w http.ResponseWriter, err error) {
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
@@ -0,0 +1,98 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockercompat
+
+import (
+	"encoding/binary"
+	"io"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	streamStdout byte = 1
+	streamStderr byte = 2
+)
+
+// containerLogs streams the named container's logs in Docker's multiplexed
+// framing: each frame is a 1-byte stream id (1=stdout, 2=stderr), 3 bytes of
+// padding, a big-endian uint32 payload length, then the payload. Docker
+// clients (including docker-compose) expect this exact shape from
+// GET /containers/{id}/logs?follow=1, so the Pod log stream is re-framed
+// into it here rather than passed through raw.
+func (h *Handler) containerLogs(w http.ResponseWriter, r *http.Request) {
+	namespace, podName, containerName, err := splitContainerID(mux.Vars(r)["id"])
+	if err != nil {
+		httpError(w, err)
+		return
+	}
+
+	follow := r.URL.Query().Get("follow") == "1" || r.URL.Query().Get("follow") == "true"
+	req := h.Client.Get().
+		Namespace(namespace).
+		Name(podName).
+		Resource("pods").
+		SubResource("log").
+		Param("container", containerName).
+		Param("follow", boolToParam(follow))
+
+	stream, err := req.Stream()
+	if err != nil {
+		httpError(w, err)
+		return
+	}
+	defer stream.Close()
+
+	w.Header().Set("Content-Type", "application/vnd.docker.raw-stream")
+	w.WriteHeader(http.StatusOK)
+	flusher, canFlush := w.(http.Flusher)
+
+	buf := make([]byte, 32*1024)
+	for {
+		n, readErr := stream.Read(buf)
+		if n > 0 {
+			if writeErr := writeDockerFrame(w, streamStdout, buf[:n]); writeErr != nil {
+				return
+			}
+			if canFlush {
+				flusher.Flush()
+			}
+		}
+		if readErr != nil {
+			return
+		}
+	}
+}
+
+func writeDockerFrame(w io.Writer, stream byte, payload []byte) error {
+	header := make([]byte, 8)
+	header[0] = stream
+	binary.BigEndian.PutUint32(header[4:], uint32(len(payload)))
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	_, err := w.Write(payload)
+	return err
+}
+
+func boolToParam(b bool) string {
+	if b {
+		return "true"
+	}
+	return "false"
+}
@@ -0,0 +1,183 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockercompat
+
+import (
+	"fmt"
+	"time"
+
+	"k8s.io/kubernetes/pkg/api"
+)
+
+// ContainerListItem mirrors the shape of an entry in Docker's
+// GET /containers/json response, as far as fields this shim can populate
+// from a Pod.
+type ContainerListItem struct {
+	Id      string            `json:"Id"`
+	Names   []string          `json:"Names"`
+	Image   string            `json:"Image"`
+	State   string            `json:"State"`
+	Status  string            `json:"Status"`
+	Labels  map[string]string `json:"Labels"`
+	Ports   []PortBinding     `json:"Ports"`
+	Created int64             `json:"Created"`
+}
+
+// PortBinding mirrors a single entry of ContainerListItem.Ports.
+type PortBinding struct {
+	PrivatePort int32  `json:"PrivatePort"`
+	PublicPort  int32  `json:"PublicPort,omitempty"`
+	Type        string `json:"Type"`
+}
+
+// ContainerJSON mirrors the shape of Docker's GET /containers/{id}/json
+// (inspect) response.
+type ContainerJSON struct {
+	Id              string                 `json:"Id"`
+	Name            string                 `json:"Name"`
+	Image           string                 `json:"Image"`
+	Created         string                 `json:"Created"`
+	State           ContainerJSONState     `json:"State"`
+	Config          ContainerJSONConfig    `json:"Config"`
+	NetworkSettings map[string]interface{} `json:"NetworkSettings"`
+}
+
+// ContainerJSONState mirrors ContainerJSON.State.
+type ContainerJSONState struct {
+	Status     string `json:"Status"`
+	Running    bool   `json:"Running"`
+	Paused     bool   `json:"Paused"`
+	Restarting bool   `json:"Restarting"`
+	ExitCode   int32  `json:"ExitCode"`
+	StartedAt  string `json:"StartedAt"`
+	FinishedAt string `json:"FinishedAt"`
+}
+
+// ContainerJSONConfig mirrors ContainerJSON.Config.
+type ContainerJSONConfig struct {
+	Image  string            `json:"Image"`
+	Labels map[string]string `json:"Labels"`
+}
+
+// containerID encodes which Pod, and which container within it, a Docker
+// compat "container id" refers to: "<namespace>/<pod>/<container>". Real
+// Docker ids are opaque hex strings, but encoding the reference directly
+// keeps this shim free of an id-allocation table to keep in sync with the
+// cluster's actual state.
+func containerID(namespace, podName, containerName string) string {
+	return fmt.Sprintf("%s/%s/%s", namespace, podName, containerName)
+}
+
+// PodToContainerListItems translates one Pod into one ContainerListItem per
+// container, the unit Docker's /containers/json enumerates.
+func PodToContainerListItems(pod *api.Pod) []ContainerListItem {
+	statusByName := map[string]api.ContainerStatus{}
+	for _, cs := range pod.Status.ContainerStatuses {
+		statusByName[cs.Name] = cs
+	}
+
+	items := make([]ContainerListItem, 0, len(pod.Spec.Containers))
+	for _, c := range pod.Spec.Containers {
+		state, status := containerState(statusByName[c.Name])
+		ports := make([]PortBinding, 0, len(c.Ports))
+		for _, p := range c.Ports {
+			ports = append(ports, PortBinding{
+				PrivatePort: p.ContainerPort,
+				PublicPort:  p.HostPort,
+				Type:        string(p.Protocol),
+			})
+		}
+		labels := map[string]string{}
+		for k, v := range pod.Labels {
+			labels[k] = v
+		}
+		labels["io.kubernetes.pod.name"] = pod.Name
+		labels["io.kubernetes.pod.namespace"] = pod.Namespace
+		labels["io.kubernetes.container.name"] = c.Name
+
+		items = append(items, ContainerListItem{
+			Id:      containerID(pod.Namespace, pod.Name, c.Name),
+			Names:   []string{fmt.Sprintf("/%s_%s", pod.Name, c.Name)},
+			Image:   c.Image,
+			State:   state,
+			Status:  status,
+			Labels:  labels,
+			Ports:   ports,
+			Created: pod.CreationTimestamp.Unix(),
+		})
+	}
+	return items
+}
+
+// PodToContainerJSON translates the named container of pod into Docker's
+// inspect shape.
+func PodToContainerJSON(pod *api.Pod, containerName string) (*ContainerJSON, error) {
+	var container *api.Container
+	for i := range pod.Spec.Containers {
+		if pod.Spec.Containers[i].Name == containerName {
+			container = &pod.Spec.Containers[i]
+			break
+		}
+	}
+	if container == nil {
+		return nil, fmt.Errorf("no container named %q in pod %s/%s", containerName, pod.Namespace, pod.Name)
+	}
+
+	var cs api.ContainerStatus
+	for _, s := range pod.Status.ContainerStatuses {
+		if s.Name == containerName {
+			cs = s
+			break
+		}
+	}
+	state, _ := containerState(cs)
+	var exitCode int32
+	if cs.State.Terminated != nil {
+		exitCode = cs.State.Terminated.ExitCode
+	}
+
+	return &ContainerJSON{
+		Id:      containerID(pod.Namespace, pod.Name, containerName),
+		Name:    fmt.Sprintf("/%s_%s", pod.Name, containerName),
+		Image:   container.Image,
+		Created: pod.CreationTimestamp.Format(time.RFC3339Nano),
+		State: ContainerJSONState{
+			Status:     state,
+			Running:    cs.State.Running != nil,
+			Restarting: cs.State.Waiting != nil,
+			ExitCode:   exitCode,
+		},
+		Config: ContainerJSONConfig{
+			Image:  container.Image,
+			Labels: pod.Labels,
+		},
+		NetworkSettings: map[string]interface{}{"IPAddress": pod.Status.PodIP},
+	}, nil
+}
+
+func containerState(cs api.ContainerStatus) (state, status string) {
+	switch {
+	case cs.State.Running != nil:
+		return "running", fmt.Sprintf("Up since %s", cs.State.Running.StartedAt)
+	case cs.State.Terminated != nil:
+		return "exited", fmt.Sprintf("Exited (%d)", cs.State.Terminated.ExitCode)
+	case cs.State.Waiting != nil:
+		return "created", cs.State.Waiting.Reason
+	default:
+		return "created", "Created"
+	}
+}
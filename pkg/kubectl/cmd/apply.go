@@ -0,0 +1,294 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"k8s.io/kubernetes/pkg/api/annotations"
+	"k8s.io/kubernetes/pkg/api/meta"
+	"k8s.io/kubernetes/pkg/api/unversioned"
+	cmdutil "k8s.io/kubernetes/pkg/kubectl/cmd/util"
+	"k8s.io/kubernetes/pkg/kubectl/resource"
+)
+
+// defaultPruneKinds lists the kinds prune considers even when the current
+// apply's manifest doesn't mention them at all -- e.g. a selector-only
+// re-apply of an empty/stdin manifest, which resolves zero infos and so
+// can't derive any candidate mappings from the current apply the way the
+// kinds an apply *did* touch can.
+var defaultPruneKinds = []unversioned.GroupKind{
+	{Kind: "ConfigMap"},
+	{Kind: "Secret"},
+	{Kind: "Pod"},
+	{Kind: "Service"},
+	{Kind: "ReplicationController"},
+	{Group: "extensions", Kind: "Deployment"},
+	{Group: "extensions", Kind: "DaemonSet"},
+	{Group: "batch", Kind: "Job"},
+}
+
+const (
+	applyLong = `Apply a configuration to a resource by filename or stdin.
+The resource name must be specified. This resource will be created if it
+doesn't exist yet. To use 'apply', always create the resource initially with
+either 'apply' or 'create --save-config'.`
+
+	applyExample = `
+		# Apply the configuration in pod.json to a pod
+		kubectl apply -f ./pod.json
+
+		# Apply a templated manifest, overriding its image tag
+		kubectl apply -f ./pod.json --set image.tag=v2
+
+		# Apply a labeled set of manifests and prune anything no longer present
+		kubectl apply -f ./manifests/ -l app=guestbook --prune`
+)
+
+// ApplyOptions holds everything Complete and Run need: where the manifest(s) come
+// from, the --set/--set-file/--values overrides RenderManifestWithValues
+// applies before the manifest is parsed, and --prune's selector.
+type ApplyOptions struct {
+	Namespace string
+	Filenames []string
+	Selector  string
+	Prune     bool
+
+	Sets       []string
+	SetFiles   []string
+	ValuesFile string
+
+	Builder *resource.Builder
+	Mapper  meta.RESTMapper
+	Factory *cmdutil.Factory
+	Out     io.Writer
+}
+
+// NewCmdApply returns the "kubectl apply" command: create-or-replace of each
+// object, recording it in the last-applied-configuration annotation (no
+// three-way merge is computed), with optional manifest templating (--set/--set-file/--values, wired to
+// RenderManifestWithValues) and --prune cleanup of previously-applied
+// objects that have dropped out of the current manifest set.
+func NewCmdApply(f *cmdutil.Factory, out io.Writer) *cobra.Command {
+	options := &ApplyOptions{Out: out}
+
+	cmd := &cobra.Command{
+		Use:     "apply -f FILENAME",
+		Short:   "Apply a configuration to a resource by filename or stdin",
+		Long:    applyLong,
+		Example: applyExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(options.Complete(f, cmd, args))
+			cmdutil.CheckErr(options.Run())
+		},
+	}
+	cmd.Flags().StringSliceVarP(&options.Filenames, "filename", "f", []string{}, "Filename, directory, or URL to files to apply, or \"-\" for stdin")
+	cmd.Flags().StringVarP(&options.Selector, "selector", "l", "", "Selector (label query) to filter on, used with --prune")
+	cmd.Flags().BoolVar(&options.Prune, "prune", false, "Delete previously-applied objects matching --selector that are no longer present in the current manifest set")
+	cmd.Flags().StringSliceVar(&options.Sets, "set", []string{}, "Set a templated manifest value (dotted.key=value), can be repeated")
+	cmd.Flags().StringSliceVar(&options.SetFiles, "set-file", []string{}, "Set a templated manifest value from a file's contents (dotted.key=path), can be repeated")
+	cmd.Flags().StringVar(&options.ValuesFile, "values", "", "Path to a YAML file of templated manifest values")
+	return cmd
+}
+
+// Complete reads and renders the manifest(s) named by --filename (applying
+// --set/--set-file/--values via RenderManifestWithValues) and builds the
+// resource.Builder that Run will use to apply them.
+func (o *ApplyOptions) Complete(f *cmdutil.Factory, cmd *cobra.Command, args []string) error {
+	namespace, _, err := f.DefaultNamespace()
+	if err != nil {
+		return err
+	}
+	o.Namespace = namespace
+	o.Factory = f
+
+	mapper, _ := f.Object()
+	o.Mapper = mapper
+
+	manifest, err := o.readManifests()
+	if err != nil {
+		return err
+	}
+	rendered, err := RenderManifestWithValues(manifest, o.Sets, o.SetFiles, o.ValuesFile)
+	if err != nil {
+		return err
+	}
+
+	o.Builder = f.NewBuilder().
+		NamespaceParam(o.Namespace).DefaultNamespace().
+		Stream(bytes.NewReader(rendered), "apply-manifest").
+		Flatten()
+	return nil
+}
+
+func (o *ApplyOptions) readManifests() ([]byte, error) {
+	var buf bytes.Buffer
+	for _, filename := range o.Filenames {
+		if filename == "-" {
+			if _, err := io.Copy(&buf, os.Stdin); err != nil {
+				return nil, fmt.Errorf("reading manifest from stdin: %v", err)
+			}
+			continue
+		}
+		contents, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return nil, fmt.Errorf("reading manifest %q: %v", filename, err)
+		}
+		buf.Write(contents)
+		buf.WriteString("\n---\n")
+	}
+	return buf.Bytes(), nil
+}
+
+// Run applies every resource the builder resolved, then -- if --prune was
+// set -- removes any previously-applied object matching --selector that
+// didn't appear in this apply, unless it carries ResourcePolicyAnnotation.
+func (o *ApplyOptions) Run() error {
+	r := o.Builder.Do()
+	infos, err := r.Infos()
+	if err != nil {
+		return err
+	}
+
+	applied := map[string]bool{}
+	for _, info := range infos {
+		if err := applyOneObject(info); err != nil {
+			return err
+		}
+		applied[pruneKey(info.Mapping.Resource, info.Namespace, info.Name)] = true
+		fmt.Fprintf(o.Out, "%s/%s\n", info.Mapping.Resource, info.Name)
+	}
+
+	if !o.Prune {
+		return nil
+	}
+	return o.prune(infos, applied)
+}
+
+// applyOneObject records info.Object's JSON in its last-applied-configuration
+// annotation, then creates the object, or replaces the live object wholesale
+// if it already exists. Unlike a strategic-merge patch against the previous
+// annotation, this does not compute a three-way merge: fields set on the
+// live object but absent from the manifest are overwritten.
+func applyOneObject(info *resource.Info) error {
+	accessor, err := meta.Accessor(info.Object)
+	if err != nil {
+		return err
+	}
+	objAnnotations := accessor.GetAnnotations()
+	if objAnnotations == nil {
+		objAnnotations = map[string]string{}
+	}
+	delete(objAnnotations, annotations.LastAppliedConfigAnnotation)
+	accessor.SetAnnotations(objAnnotations)
+	applied, err := json.Marshal(info.Object)
+	if err != nil {
+		return err
+	}
+	objAnnotations[annotations.LastAppliedConfigAnnotation] = string(applied)
+	accessor.SetAnnotations(objAnnotations)
+
+	helper := resource.NewHelper(info.Client, info.Mapping)
+	if _, err := helper.Get(info.Namespace, info.Name, false); err != nil {
+		_, err := helper.Create(info.Namespace, true, info.Object)
+		return err
+	}
+	_, err = helper.Replace(info.Namespace, info.Name, true, info.Object)
+	return err
+}
+
+func pruneKey(resourceName, namespace, name string) string {
+	return namespace + "/" + resourceName + "/" + name
+}
+
+// prune deletes every object matching o.Selector, across both the mappings
+// this apply touched and defaultPruneKinds, that isn't in applied -- unless
+// it carries ResourcePolicyAnnotation, in which case it's left in place and
+// detached from apply's bookkeeping instead (its last-applied-configuration
+// annotation is removed).
+//
+// defaultPruneKinds has to be consulted as well as infos: a selector-only
+// re-apply of an empty or stdin manifest resolves zero infos, and a manifest
+// that simply no longer mentions a kind it used to create resolves infos for
+// every *other* kind but that one -- in both cases the stale objects' kind
+// would never be scanned if mappings came only from the current infos.
+func (o *ApplyOptions) prune(infos []*resource.Info, applied map[string]bool) error {
+	mappings := map[string]*meta.RESTMapping{}
+	for _, info := range infos {
+		mappings[info.Mapping.Resource] = info.Mapping
+	}
+	for _, gk := range defaultPruneKinds {
+		mapping, err := o.Mapper.RESTMapping(gk, "")
+		if err != nil {
+			// Not every server registers every kind in defaultPruneKinds (e.g.
+			// extensions/Deployment on an old apiserver); skip what it doesn't have.
+			continue
+		}
+		if _, ok := mappings[mapping.Resource]; !ok {
+			mappings[mapping.Resource] = mapping
+		}
+	}
+
+	for resourceName, mapping := range mappings {
+		client, err := o.Factory.ClientForMapping(mapping)
+		if err != nil {
+			return err
+		}
+		helper := resource.NewHelper(client, mapping)
+		objs, err := helper.List(o.Namespace, mapping.APIVersion, o.Selector, false)
+		if err != nil {
+			return err
+		}
+		candidates, err := meta.ExtractList(objs)
+		if err != nil {
+			return err
+		}
+		for _, obj := range candidates {
+			accessor, err := meta.Accessor(obj)
+			if err != nil {
+				return err
+			}
+			if applied[pruneKey(resourceName, accessor.GetNamespace(), accessor.GetName())] {
+				continue
+			}
+
+			objAnnotations := accessor.GetAnnotations()
+			if ShouldRetainOnDelete(objAnnotations) {
+				DetachFromAppliedConfiguration(objAnnotations)
+				accessor.SetAnnotations(objAnnotations)
+				if _, err := helper.Replace(accessor.GetNamespace(), accessor.GetName(), true, obj); err != nil {
+					return err
+				}
+				fmt.Fprintf(o.Out, "%s/%s retained (keep-on-delete)\n", resourceName, accessor.GetName())
+				continue
+			}
+
+			if err := helper.Delete(accessor.GetNamespace(), accessor.GetName()); err != nil {
+				return err
+			}
+			fmt.Fprintf(o.Out, "%s/%s pruned\n", resourceName, accessor.GetName())
+		}
+	}
+	return nil
+}
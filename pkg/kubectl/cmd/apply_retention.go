@@ -0,0 +1,42 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"k8s.io/kubernetes/pkg/api/annotations"
+)
+
+// ResourcePolicyAnnotation marks an object as exempt from apply's prune and
+// delete-by-manifest behavior, mirroring Helm's "helm.sh/resource-policy: keep"
+// escape hatch. When set to "keep", a subsequent "kubectl apply -f" that omits
+// the object, or a "kubectl delete -f" of the manifest that originally created
+// it, must leave the object in place instead of removing it.
+const ResourcePolicyAnnotation = "kubectl.kubernetes.io/keep-on-delete"
+
+// ShouldRetainOnDelete reports whether an object carrying these annotations
+// should survive an apply-driven prune or a delete-by-manifest.
+func ShouldRetainOnDelete(objAnnotations map[string]string) bool {
+	return objAnnotations[ResourcePolicyAnnotation] == "keep"
+}
+
+// DetachFromAppliedConfiguration strips the last-applied-configuration
+// bookkeeping annotation from an object being retained, so that once it is
+// orphaned by prune/delete it no longer carries apply's record of a manifest
+// that no longer references it.
+func DetachFromAppliedConfiguration(objAnnotations map[string]string) {
+	delete(objAnnotations, annotations.LastAppliedConfigAnnotation)
+}
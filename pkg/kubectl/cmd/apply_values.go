@@ -0,0 +1,168 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"text/template"
+
+	"github.com/ghodss/yaml"
+)
+
+// ParseSetValues turns a list of "--set" arguments (each "dot.separated.key=value")
+// into a nested map suitable for use as text/template data, following the
+// same dotted-key convention Helm uses for its own --set flag.
+func ParseSetValues(sets []string) (map[string]interface{}, error) {
+	result := map[string]interface{}{}
+	for _, set := range sets {
+		eq := strings.Index(set, "=")
+		if eq < 0 {
+			return nil, fmt.Errorf("malformed --set value %q: expected key=value", set)
+		}
+		key, value := set[:eq], set[eq+1:]
+		if key == "" {
+			return nil, fmt.Errorf("malformed --set value %q: empty key", set)
+		}
+		if err := setNestedValue(result, strings.Split(key, "."), value); err != nil {
+			return nil, fmt.Errorf("malformed --set value %q: %v", set, err)
+		}
+	}
+	return result, nil
+}
+
+// ParseSetFileValues is like ParseSetValues but each "--set-file" argument is
+// "dot.separated.key=path", and the value substituted is the contents of the
+// file at path rather than the literal string after "=".
+func ParseSetFileValues(setFiles []string) (map[string]interface{}, error) {
+	result := map[string]interface{}{}
+	for _, setFile := range setFiles {
+		eq := strings.Index(setFile, "=")
+		if eq < 0 {
+			return nil, fmt.Errorf("malformed --set-file value %q: expected key=path", setFile)
+		}
+		key, filePath := setFile[:eq], setFile[eq+1:]
+		contents, err := ioutil.ReadFile(filePath)
+		if err != nil {
+			return nil, fmt.Errorf("reading --set-file path %q: %v", filePath, err)
+		}
+		if err := setNestedValue(result, strings.Split(key, "."), string(contents)); err != nil {
+			return nil, fmt.Errorf("malformed --set-file value %q: %v", setFile, err)
+		}
+	}
+	return result, nil
+}
+
+// ParseValuesFile parses a YAML "--values" file into the same nested-map
+// shape ParseSetValues produces, so the two can be merged with MergeValues.
+func ParseValuesFile(filePath string) (map[string]interface{}, error) {
+	contents, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("reading --values file %q: %v", filePath, err)
+	}
+	result := map[string]interface{}{}
+	if err := yaml.Unmarshal(contents, &result); err != nil {
+		return nil, fmt.Errorf("parsing --values file %q: %v", filePath, err)
+	}
+	return result, nil
+}
+
+// MergeValues merges override on top of base, recursively for nested maps.
+// override wins on conflicting scalar keys, which gives "--set" precedence
+// over "--values" when both are supplied to "kubectl apply".
+func MergeValues(base, override map[string]interface{}) map[string]interface{} {
+	merged := map[string]interface{}{}
+	for k, v := range base {
+		merged[k] = v
+	}
+	for k, v := range override {
+		if overrideMap, ok := v.(map[string]interface{}); ok {
+			if baseMap, ok := merged[k].(map[string]interface{}); ok {
+				merged[k] = MergeValues(baseMap, overrideMap)
+				continue
+			}
+		}
+		merged[k] = v
+	}
+	return merged
+}
+
+// RenderManifestWithValues runs manifest through text/template using the
+// values collected from --set, --set-file, and --values (in that precedence
+// order, highest first), and is the hook ApplyOptions.Complete calls on the input stream
+// before building objects, so "kubectl apply --set image.tag=v2 -f -" can
+// parameterize a manifest the same way "kubectl apply -f -" consumes a
+// plain one.
+func RenderManifestWithValues(manifest []byte, sets, setFiles []string, valuesFile string) ([]byte, error) {
+	values := map[string]interface{}{}
+	if valuesFile != "" {
+		fileValues, err := ParseValuesFile(valuesFile)
+		if err != nil {
+			return nil, err
+		}
+		values = MergeValues(values, fileValues)
+	}
+	setFileValues, err := ParseSetFileValues(setFiles)
+	if err != nil {
+		return nil, err
+	}
+	values = MergeValues(values, setFileValues)
+	setValues, err := ParseSetValues(sets)
+	if err != nil {
+		return nil, err
+	}
+	values = MergeValues(values, setValues)
+
+	if len(values) == 0 {
+		return manifest, nil
+	}
+	return renderTemplate(manifest, values)
+}
+
+func renderTemplate(manifest []byte, values map[string]interface{}) ([]byte, error) {
+	tmpl, err := template.New("manifest").Parse(string(manifest))
+	if err != nil {
+		return nil, fmt.Errorf("parsing manifest as a template: %v", err)
+	}
+	var rendered bytes.Buffer
+	if err := tmpl.Execute(&rendered, values); err != nil {
+		return nil, fmt.Errorf("rendering manifest with values %v: %v", values, err)
+	}
+	return rendered.Bytes(), nil
+}
+
+func setNestedValue(root map[string]interface{}, keys []string, value string) error {
+	m := root
+	for _, key := range keys[:len(keys)-1] {
+		next, ok := m[key]
+		if !ok {
+			nextMap := map[string]interface{}{}
+			m[key] = nextMap
+			m = nextMap
+			continue
+		}
+		nextMap, ok := next.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("key %q already has a scalar value", key)
+		}
+		m = nextMap
+	}
+	m[keys[len(keys)-1]] = value
+	return nil
+}
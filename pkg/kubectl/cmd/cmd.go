@@ -0,0 +1,44 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"io"
+
+	"github.com/spf13/cobra"
+
+	cmdutil "k8s.io/kubernetes/pkg/kubectl/cmd/util"
+)
+
+// AddExperimentalCommands registers the cmd package's experimental
+// subcommands (those still gated behind "Experimental:" in their Short text,
+// such as "wait") onto root. It is called from NewKubectlCommand's main
+// AddCommand block alongside the stable subcommands; it lives in its own
+// function here because this snapshot only carries the experimental command
+// files, not the rest of the kubectl command tree.
+func AddExperimentalCommands(root *cobra.Command, f *cmdutil.Factory, out io.Writer) {
+	root.AddCommand(NewCmdWait(f, out))
+}
+
+// AddStableCommands registers this package's stable subcommands (apply,
+// delete, proxy) onto root, alongside AddExperimentalCommands and whatever
+// of the rest of the stable command tree isn't part of this snapshot.
+func AddStableCommands(root *cobra.Command, f *cmdutil.Factory, out io.Writer) {
+	root.AddCommand(NewCmdApply(f, out))
+	root.AddCommand(NewCmdDelete(f, out))
+	root.AddCommand(NewCmdProxy(f, out))
+}
@@ -0,0 +1,146 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+
+	apierrs "k8s.io/kubernetes/pkg/api/errors"
+	"k8s.io/kubernetes/pkg/api/meta"
+	cmdutil "k8s.io/kubernetes/pkg/kubectl/cmd/util"
+	"k8s.io/kubernetes/pkg/kubectl/resource"
+)
+
+const (
+	deleteLong = `Delete resources by filenames, stdin, resources and names, or by resources
+and label selector.`
+
+	deleteExample = `
+		# Delete a pod using the type and name specified in pod.json
+		kubectl delete -f ./pod.json
+
+		# Delete resources from a set of manifests, but leave any marked
+		# keep-on-delete in place
+		kubectl delete -f ./manifests/ -l app=guestbook`
+)
+
+// DeleteOptions holds everything Complete and Run need: where the manifest(s)
+// come from, plus --grace-period/--ignore-not-found. IgnoreNotFound is
+// honored by deleteOneObject; GracePeriod is parsed but not sent, since
+// resource.Helper.Delete issues the delete without any delete options.
+type DeleteOptions struct {
+	Filenames      []string
+	Selector       string
+	GracePeriod    int
+	IgnoreNotFound bool
+
+	Builder *resource.Builder
+	Out     io.Writer
+}
+
+// NewCmdDelete returns the "kubectl delete" command: delete-by-filename/
+// selector/name, honoring ResourcePolicyAnnotation the same way apply's
+// --prune does -- an object marked keep-on-delete survives "delete -f" and
+// is only detached from the last-applied-configuration bookkeeping instead.
+func NewCmdDelete(f *cmdutil.Factory, out io.Writer) *cobra.Command {
+	options := &DeleteOptions{Out: out}
+
+	cmd := &cobra.Command{
+		Use:     "delete ([-f FILENAME] | TYPE [NAME | -l label])",
+		Short:   "Delete resources by filenames, stdin, resources and names, or by resources and label selector",
+		Long:    deleteLong,
+		Example: deleteExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(options.Complete(f, cmd, args))
+			cmdutil.CheckErr(options.Run())
+		},
+	}
+	cmd.Flags().StringSliceVarP(&options.Filenames, "filename", "f", []string{}, "Filename, directory, or URL to files identifying the resource(s) to delete, or \"-\" for stdin")
+	cmd.Flags().StringVarP(&options.Selector, "selector", "l", "", "Selector (label query) to filter on")
+	cmd.Flags().IntVar(&options.GracePeriod, "grace-period", -1, "Period of time in seconds given to the resource to terminate gracefully. Parsed but not sent with the delete request.")
+	cmd.Flags().BoolVar(&options.IgnoreNotFound, "ignore-not-found", false, "Treat \"resource not found\" as a successful delete")
+	return cmd
+}
+
+// Complete resolves args/filenames/selector into the resource.Builder Run
+// will iterate.
+func (o *DeleteOptions) Complete(f *cmdutil.Factory, cmd *cobra.Command, args []string) error {
+	namespace, _, err := f.DefaultNamespace()
+	if err != nil {
+		return err
+	}
+
+	o.Builder = f.NewBuilder().
+		NamespaceParam(namespace).DefaultNamespace().
+		FilenameParam(false, o.Filenames...).
+		SelectorParam(o.Selector).
+		ResourceTypeOrNameArgs(false, args...).
+		Flatten()
+	return nil
+}
+
+// Run deletes every object the builder resolved, skipping (and detaching
+// from apply's bookkeeping, rather than deleting) any object for which
+// ShouldRetainOnDelete is true.
+func (o *DeleteOptions) Run() error {
+	r := o.Builder.Do()
+	infos, err := r.Infos()
+	if err != nil {
+		return err
+	}
+
+	for _, info := range infos {
+		if err := o.deleteOneObject(info); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteOneObject either deletes info, or -- if it carries
+// ResourcePolicyAnnotation -- detaches it from the last-applied-configuration
+// bookkeeping and leaves it in place, printing which happened.
+func (o *DeleteOptions) deleteOneObject(info *resource.Info) error {
+	accessor, err := meta.Accessor(info.Object)
+	if err != nil {
+		return err
+	}
+	helper := resource.NewHelper(info.Client, info.Mapping)
+
+	objAnnotations := accessor.GetAnnotations()
+	if ShouldRetainOnDelete(objAnnotations) {
+		DetachFromAppliedConfiguration(objAnnotations)
+		accessor.SetAnnotations(objAnnotations)
+		if _, err := helper.Replace(info.Namespace, info.Name, true, info.Object); err != nil {
+			return err
+		}
+		fmt.Fprintf(o.Out, "%s/%s retained (keep-on-delete)\n", info.Mapping.Resource, info.Name)
+		return nil
+	}
+
+	if err := helper.Delete(info.Namespace, info.Name); err != nil {
+		if o.IgnoreNotFound && apierrs.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	fmt.Fprintf(o.Out, "%s/%s deleted\n", info.Mapping.Resource, info.Name)
+	return nil
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+
+	"github.com/spf13/cobra"
+
+	kubeclient "k8s.io/kubernetes/pkg/client/unversioned"
+	cmdutil "k8s.io/kubernetes/pkg/kubectl/cmd/util"
+)
+
+const (
+	proxyLong = `To proxy all of the kubernetes api and nothing else, use:
+
+    $ kubectl proxy --api-prefix=/
+
+To proxy only part of the kubernetes api and also some static files:
+
+    $ kubectl proxy --www=/my/files --www-prefix=/static/ --api-prefix=/api/
+
+The server will serve files under the www directory under the specified
+prefix, and requests matching any --reject-paths expression are refused
+with 403 regardless of which prefix they'd otherwise fall under.`
+
+	proxyExample = `
+		# Run a proxy to the api server on port 8011, serving static content from ./local/www/
+		kubectl proxy --port=8011 --www=./local/www/
+
+		# Disallow requests made to the kubernetes api of kind secrets or pods
+		kubectl proxy --reject-paths='^/api/.*/(secrets|pods)'`
+)
+
+// NewCmdProxy returns the "kubectl proxy" command: a local reverse proxy in
+// front of the configured apiserver, with --www/--www-prefix to also serve
+// static files and --reject-paths to refuse matching requests outright.
+// Flag parsing and handler construction are delegated to ProxyServerOptions
+// and NewProxyHandler so this file only wires cobra and the net.Listener.
+func NewCmdProxy(f *cmdutil.Factory, out io.Writer) *cobra.Command {
+	var (
+		port        int
+		address     string
+		apiPrefix   string
+		wwwPrefix   string
+		wwwDir      string
+		unixSocket  string
+		rejectPaths []string
+	)
+
+	cmd := &cobra.Command{
+		Use:     "proxy",
+		Short:   "Run a proxy to the Kubernetes API server",
+		Long:    proxyLong,
+		Example: proxyExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			opts := ProxyServerOptions{
+				StaticDir:    wwwDir,
+				StaticPrefix: wwwPrefix,
+				APIPrefix:    apiPrefix,
+				RejectPaths:  rejectPaths,
+			}
+			cmdutil.CheckErr(RunProxy(f, out, opts, address, port, unixSocket))
+		},
+	}
+	cmd.Flags().StringVarP(&address, "address", "", "127.0.0.1", "The IP address on which to serve on")
+	cmd.Flags().IntVarP(&port, "port", "p", 8001, "The port on which to run the proxy. Set to 0 to pick a random port")
+	cmd.Flags().StringVar(&apiPrefix, "api-prefix", "/", "Prefix to serve the proxied API under")
+	cmd.Flags().StringVar(&wwwDir, "www", "", "Also serve static files from the given directory under --www-prefix")
+	cmd.Flags().StringVar(&wwwPrefix, "www-prefix", "/static/", "Prefix to serve static files under, used with --www")
+	cmd.Flags().StringSliceVar(&rejectPaths, "reject-paths", nil, "Regular expressions of paths to reject, formatted as a regexp. Can be used multiple times, and paths are checked against all of them.")
+	cmd.Flags().StringVar(&unixSocket, "unix-socket", "", "Unix socket on which to run the proxy, instead of --address/--port")
+	return cmd
+}
+
+// RunProxy builds the reverse proxy to the configured apiserver via
+// NewProxyHandler, routes its apiserver-facing leg through
+// kubeclient.TransportFor (so ALL_PROXY/NO_PROXY are honored the same way
+// the rest of kubectl's REST traffic is), and serves it on either a TCP
+// address:port or, if unixSocket is non-empty, a unix domain socket.
+func RunProxy(f *cmdutil.Factory, out io.Writer, opts ProxyServerOptions, address string, port int, unixSocket string) error {
+	clientConfig, err := f.ClientConfig()
+	if err != nil {
+		return err
+	}
+	apiURL, err := url.Parse(clientConfig.Host)
+	if err != nil {
+		return fmt.Errorf("parsing apiserver host %q: %v", clientConfig.Host, err)
+	}
+	apiProxy := httputil.NewSingleHostReverseProxy(apiURL)
+	apiProxy.Transport, err = kubeclient.TransportFor(clientConfig)
+	if err != nil {
+		return fmt.Errorf("building transport to %s: %v", apiURL, err)
+	}
+
+	handler, err := NewProxyHandler(opts, apiProxy)
+	if err != nil {
+		return err
+	}
+
+	var l net.Listener
+	if unixSocket != "" {
+		l, err = net.Listen("unix", unixSocket)
+	} else {
+		l, err = net.Listen("tcp", fmt.Sprintf("%s:%d", address, port))
+	}
+	if err != nil {
+		return err
+	}
+
+	if tcpAddr, ok := l.Addr().(*net.TCPAddr); ok {
+		fmt.Fprintf(out, "Starting to serve on %s\n", fmt.Sprintf("%s:%d", address, tcpAddr.Port))
+	} else {
+		fmt.Fprintf(out, "Starting to serve on %s\n", l.Addr().String())
+	}
+	return http.Serve(l, handler)
+}
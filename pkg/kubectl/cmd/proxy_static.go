@@ -0,0 +1,98 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// ProxyServerOptions configures the static-file and API routing that
+// "kubectl proxy" layers in front of the apiserver reverse proxy.
+type ProxyServerOptions struct {
+	// StaticDir is served under StaticPrefix when non-empty. Equivalent to
+	// --www.
+	StaticDir string
+	// StaticPrefix is the URL prefix StaticDir is served under. Equivalent
+	// to --www-prefix. Defaults to "/static/".
+	StaticPrefix string
+	// APIPrefix is the URL prefix API calls are routed under. Equivalent to
+	// --api-prefix. Defaults to "/". A prefix that doesn't itself start with
+	// "/api" is stripped before forwarding, so "/custom-api/api/v1" reaches
+	// the apiserver as "/api/v1"; one that does is forwarded unchanged.
+	APIPrefix string
+	// RejectPaths is a list of regular expressions; requests whose path
+	// matches any of them are refused with 403 regardless of which prefix
+	// they'd otherwise fall under. Equivalent to --reject-paths.
+	RejectPaths []string
+}
+
+// NewProxyHandler builds the combined http.Handler kubectl proxy serves:
+// requests under APIPrefix are forwarded to apiProxy, requests under
+// StaticPrefix are served as files from StaticDir, requests matching
+// RejectPaths are refused with 403, and everything else 404s.
+func NewProxyHandler(opts ProxyServerOptions, apiProxy http.Handler) (http.Handler, error) {
+	apiPrefix := opts.APIPrefix
+	if apiPrefix == "" {
+		apiPrefix = "/"
+	}
+	staticPrefix := opts.StaticPrefix
+	if staticPrefix == "" {
+		staticPrefix = "/static/"
+	}
+
+	rejectRegexps := make([]*regexp.Regexp, 0, len(opts.RejectPaths))
+	for _, p := range opts.RejectPaths {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --reject-paths expression %q: %v", p, err)
+		}
+		rejectRegexps = append(rejectRegexps, re)
+	}
+
+	apiHandler := apiProxy
+	if !strings.HasPrefix(apiPrefix, "/api") {
+		apiHandler = http.StripPrefix(strings.TrimSuffix(apiPrefix, "/"), apiProxy)
+	}
+
+	var staticHandler http.Handler
+	if opts.StaticDir != "" {
+		staticHandler = http.StripPrefix(staticPrefix, http.FileServer(http.Dir(opts.StaticDir)))
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		for _, re := range rejectRegexps {
+			if re.MatchString(r.URL.Path) {
+				http.Error(w, "403 Forbidden: path rejected by --reject-paths", http.StatusForbidden)
+				return
+			}
+		}
+
+		switch {
+		case staticHandler != nil && strings.HasPrefix(r.URL.Path, staticPrefix):
+			staticHandler.ServeHTTP(w, r)
+		case strings.HasPrefix(r.URL.Path, apiPrefix):
+			apiHandler.ServeHTTP(w, r)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	return mux, nil
+}
@@ -0,0 +1,196 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"k8s.io/kubernetes/pkg/api"
+	"k8s.io/kubernetes/pkg/apis/batch"
+	"k8s.io/kubernetes/pkg/apis/extensions"
+	cmdutil "k8s.io/kubernetes/pkg/kubectl/cmd/util"
+	"k8s.io/kubernetes/pkg/kubectl/resource"
+	"k8s.io/kubernetes/pkg/util/wait"
+)
+
+const (
+	waitLong = `Wait for one or more resources to satisfy a condition, or until a timeout
+elapses, blocking until either happens.
+
+Supported conditions:
+
+  --for=condition=Ready       wait until the resource's "Ready" condition is True
+  --for=condition=Available   wait until the resource's "Available" condition is True
+  --for=delete                wait until the resource no longer exists
+`
+	waitExample = `
+		# Wait for a pod to become ready
+		kubectl wait --for=condition=Ready pod/nginx --timeout=60s
+
+		# Wait for a deployment to be deleted
+		kubectl wait --for=delete deployment/nginx --timeout=5m`
+)
+
+// WaitOptions holds the parsed --for/--timeout and the resources to wait on.
+type WaitOptions struct {
+	Namespace    string
+	ResourceArgs []string
+	ForCondition string
+	ForDelete    bool
+	Timeout      time.Duration
+
+	Builder *resource.Builder
+	Out     io.Writer
+}
+
+// NewCmdWait blocks until a set of resources satisfies a condition
+// (--for=condition=<type>) or is deleted (--for=delete), or until --timeout
+// elapses. It exits non-zero on timeout so callers (scripts, CI) can rely on
+// the exit code rather than scraping output, the same way the kubectl run/
+// rolling-update e2e suites previously had to poll framework helpers by hand.
+func NewCmdWait(f *cmdutil.Factory, out io.Writer) *cobra.Command {
+	options := &WaitOptions{Out: out}
+
+	cmd := &cobra.Command{
+		Use:     "wait (RESOURCE NAME | RESOURCE/NAME) --for=[delete|condition=condition-name]",
+		Short:   "Experimental: Wait for a specific condition on one or many resources",
+		Long:    waitLong,
+		Example: waitExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(options.Complete(f, cmd, args))
+			cmdutil.CheckErr(options.Run())
+		},
+	}
+	cmd.Flags().String("for", "", "The condition to wait on: delete, or condition=condition-name")
+	cmd.Flags().Duration("timeout", 30*time.Second, "The length of time to wait before giving up, zero means check once and don't wait")
+	return cmd
+}
+
+// Complete fills in Namespace, ResourceArgs, ForCondition/ForDelete, and
+// Timeout from cmd's flags and positional args.
+func (o *WaitOptions) Complete(f *cmdutil.Factory, cmd *cobra.Command, args []string) error {
+	namespace, _, err := f.DefaultNamespace()
+	if err != nil {
+		return err
+	}
+	o.Namespace = namespace
+	o.ResourceArgs = args
+
+	forCondition := cmdutil.GetFlagString(cmd, "for")
+	switch {
+	case forCondition == "delete":
+		o.ForDelete = true
+	case strings.HasPrefix(forCondition, "condition="):
+		o.ForCondition = strings.TrimPrefix(forCondition, "condition=")
+	default:
+		return fmt.Errorf("--for must be 'delete' or 'condition=<condition-name>', got %q", forCondition)
+	}
+
+	o.Timeout = cmdutil.GetFlagDuration(cmd, "timeout")
+	o.Builder = f.NewBuilder()
+	return nil
+}
+
+// Run polls each resolved resource until it satisfies the requested
+// condition or the timeout elapses, returning an error (and thus a non-zero
+// exit code) on timeout.
+func (o *WaitOptions) Run() error {
+	r := o.Builder.
+		NamespaceParam(o.Namespace).DefaultNamespace().
+		ResourceTypeOrNameArgs(false, o.ResourceArgs...).
+		Flatten().
+		Do()
+	infos, err := r.Infos()
+	if err != nil {
+		return err
+	}
+
+	deadline := time.Now().Add(o.Timeout)
+	for _, info := range infos {
+		if err := o.waitForInfo(info, deadline); err != nil {
+			return err
+		}
+		fmt.Fprintf(o.Out, "%s/%s condition met\n", info.Mapping.Resource, info.Name)
+	}
+	return nil
+}
+
+func (o *WaitOptions) waitForInfo(info *resource.Info, deadline time.Time) error {
+	condition := func() (bool, error) {
+		obj, err := resource.NewHelper(info.Client, info.Mapping).Get(info.Namespace, info.Name, false)
+		if err != nil {
+			if o.ForDelete && isNotFoundErr(err) {
+				return true, nil
+			}
+			return false, nil
+		}
+		if o.ForDelete {
+			return false, nil
+		}
+		return conditionTrue(obj, o.ForCondition)
+	}
+	timeout := deadline.Sub(time.Now())
+	if timeout <= 0 {
+		// wait.PollImmediate treats a zero timeout as no timeout at all, so a
+		// spent (or zero) deadline is a single check here.
+		done, err := condition()
+		if err != nil {
+			return err
+		}
+		if !done {
+			return wait.ErrWaitTimeout
+		}
+		return nil
+	}
+	return wait.PollImmediate(2*time.Second, timeout, condition)
+}
+
+func isNotFoundErr(err error) bool {
+	return strings.Contains(err.Error(), "not found")
+}
+
+// conditionTrue inspects obj's .status.conditions (present on Pods,
+// Deployments, Jobs, and similar types) for an entry of the given type with
+// status "True".
+func conditionTrue(obj interface{}, conditionType string) (bool, error) {
+	switch o := obj.(type) {
+	case *api.Pod:
+		for _, c := range o.Status.Conditions {
+			if string(c.Type) == conditionType {
+				return c.Status == api.ConditionTrue, nil
+			}
+		}
+	case *extensions.Deployment:
+		for _, c := range o.Status.Conditions {
+			if string(c.Type) == conditionType {
+				return c.Status == api.ConditionTrue, nil
+			}
+		}
+	case *batch.Job:
+		for _, c := range o.Status.Conditions {
+			if string(c.Type) == conditionType {
+				return c.Status == api.ConditionTrue, nil
+			}
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,145 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package net
+
+import (
+	"bufio"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"golang.org/x/net/proxy"
+)
+
+// ProxierWithNoProxyCIDR wraps a *http.Transport's Proxy func so that hosts
+// matching NO_PROXY/no_proxy are never routed through a configured proxy,
+// even when the NO_PROXY entry is a bare host, a host:port pair, or a CIDR
+// (http.ProxyFromEnvironment only matches exact hostnames and domain
+// suffixes). Kubectl uses this to guarantee --host bypasses HTTPS_PROXY/
+// ALL_PROXY when the apiserver's address is listed in NO_PROXY.
+func ProxierWithNoProxyCIDR(delegate func(req *http.Request) (*url.URL, error)) func(req *http.Request) (*url.URL, error) {
+	return func(req *http.Request) (*url.URL, error) {
+		host := req.URL.Hostname()
+		if noProxyMatches(host) {
+			return nil, nil
+		}
+		return delegate(req)
+	}
+}
+
+func noProxyMatches(host string) bool {
+	noProxy := os.Getenv("NO_PROXY")
+	if noProxy == "" {
+		noProxy = os.Getenv("no_proxy")
+	}
+	if noProxy == "" {
+		return false
+	}
+	ip := net.ParseIP(host)
+	for _, entry := range strings.Split(noProxy, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if entry == "*" {
+			return true
+		}
+		if _, cidr, err := net.ParseCIDR(entry); err == nil && ip != nil {
+			if cidr.Contains(ip) {
+				return true
+			}
+			continue
+		}
+		entryHost := entry
+		if h, _, err := net.SplitHostPort(entry); err == nil {
+			entryHost = h
+		}
+		entryHost = strings.TrimPrefix(entryHost, ".")
+		if host == entryHost || strings.HasSuffix(host, "."+entryHost) {
+			return true
+		}
+	}
+	return false
+}
+
+// DialerForProxyURL returns a net.Dial-compatible function that connects
+// through proxyURL. It supports "http"/"https" (CONNECT, with optional Basic
+// auth taken from the URL's userinfo) and "socks5" schemes, so ALL_PROXY can
+// point at either kind of proxy. This backs kubectl's transport when
+// ALL_PROXY is set, since http.ProxyFromEnvironment alone only understands
+// HTTP(S) proxies.
+func DialerForProxyURL(proxyURL *url.URL) (func(network, addr string) (net.Conn, error), error) {
+	switch proxyURL.Scheme {
+	case "socks5", "socks5h":
+		auth := &proxy.Auth{}
+		if proxyURL.User != nil {
+			auth.User = proxyURL.User.Username()
+			auth.Password, _ = proxyURL.User.Password()
+		}
+		dialer, err := proxy.SOCKS5("tcp", proxyURL.Host, auth, proxy.Direct)
+		if err != nil {
+			return nil, fmt.Errorf("building SOCKS5 dialer for %s: %v", proxyURL, err)
+		}
+		return dialer.Dial, nil
+	case "http", "https":
+		return func(network, addr string) (net.Conn, error) {
+			return dialViaHTTPConnect(proxyURL, addr)
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q", proxyURL.Scheme)
+	}
+}
+
+func dialViaHTTPConnect(proxyURL *url.URL, addr string) (net.Conn, error) {
+	conn, err := net.Dial("tcp", proxyURL.Host)
+	if err != nil {
+		return nil, err
+	}
+	if proxyURL.Scheme == "https" {
+		conn = tls.Client(conn, &tls.Config{ServerName: proxyURL.Hostname()})
+	}
+	connectReq := &http.Request{
+		Method: "CONNECT",
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: make(http.Header),
+	}
+	if proxyURL.User != nil {
+		password, _ := proxyURL.User.Password()
+		connectReq.SetBasicAuth(proxyURL.User.Username(), password)
+	}
+	if err := connectReq.Write(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	// Consume the proxy's CONNECT response so the caller's TLS/plaintext
+	// handshake starts on the tunneled stream.
+	resp, err := http.ReadResponse(bufio.NewReader(conn), connectReq)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("proxy CONNECT to %s failed: %s", addr, resp.Status)
+	}
+	return conn, nil
+}
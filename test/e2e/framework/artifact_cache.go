@@ -0,0 +1,304 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/boltdb/bolt"
+)
+
+// artifactBucket is the boltdb bucket holding (binName, platform,
+// sourceHash) -> artifactRecord mappings.
+var artifactBucket = []byte("artifacts")
+
+// binPrefixes are the locations findBinary historically scanned, in
+// preference order, for a prebuilt binary.
+var binPrefixes = []string{
+	"_output/dockerized/bin",
+	"_output/local/bin",
+	"platforms",
+}
+
+// CacheImporter fetches an artifact identified by digest from a remote
+// store (e.g. a GCS bucket holding prebuilt kubectl binaries) into w, for
+// use when the content-addressable cache has a digest on record but the
+// local blob has since been evicted.
+type CacheImporter interface {
+	Import(digest string, w io.Writer) error
+}
+
+// artifactRecord is what ArtifactResolver persists in boltdb per
+// (name, platform, sourceHash) key.
+type artifactRecord struct {
+	Digest string    `json:"digest"`
+	MTime  time.Time `json:"mtime"`
+}
+
+// ArtifactHandle is a resolved reference to an artifact; it may or may not
+// have a local blob yet, depending on whether Materialize has been called.
+type ArtifactHandle struct {
+	Digest   string
+	resolver *ArtifactResolver
+}
+
+// Materialize ensures the handle's blob is present under the resolver's
+// content store and returns its path, importing it via the resolver's
+// CacheImporter first if it is missing locally.
+func (h *ArtifactHandle) Materialize() (string, error) {
+	return h.resolver.materialize(h.Digest)
+}
+
+// ArtifactResolver indexes e2e artifacts (kubectl, test binaries, test
+// images) by content digest under CacheDir, the way BuildKit's cache and
+// cacheimport packages index build outputs, instead of the mtime scan
+// findBinary historically did. A small boltdb store maps
+// (binName, platform, sourceHash) to the resolved digest, so repeated
+// Resolve calls -- including from parallel Ginkgo nodes sharing CacheDir on
+// the same test host -- don't re-stat every candidate path.
+type ArtifactResolver struct {
+	CacheDir string
+	Importer CacheImporter
+
+	once sync.Once
+	db   *bolt.DB
+	err  error
+}
+
+// NewArtifactResolver returns a resolver rooted at cacheDir (conventionally
+// "<RepoRoot>/_output/cache"). importer may be nil if no remote cache import
+// is configured.
+func NewArtifactResolver(cacheDir string, importer CacheImporter) *ArtifactResolver {
+	return &ArtifactResolver{CacheDir: cacheDir, Importer: importer}
+}
+
+func (r *ArtifactResolver) open() error {
+	r.once.Do(func() {
+		if err := os.MkdirAll(r.CacheDir, 0755); err != nil {
+			r.err = err
+			return
+		}
+		r.db, r.err = bolt.Open(filepath.Join(r.CacheDir, "metadata.db"), 0644, &bolt.Options{Timeout: 10 * time.Second})
+		if r.err != nil {
+			return
+		}
+		r.err = r.db.Update(func(tx *bolt.Tx) error {
+			_, err := tx.CreateBucketIfNotExists(artifactBucket)
+			return err
+		})
+	})
+	return r.err
+}
+
+// Resolve returns a handle for the named binary built for platform, keyed
+// additionally by sourceHash (e.g. a hash of the source tree that produced
+// it, so a stale cache entry from before a rebuild is never returned). If
+// no cache entry exists yet, Resolve falls back to scanning binPrefixes --
+// findBinary's original behavior -- indexes whatever it finds by content
+// hash, and returns a handle for that. An empty sourceHash is never looked
+// up in or recorded to the cache, so every such call rescans binPrefixes.
+func (r *ArtifactResolver) Resolve(name, platform, sourceHash string) (*ArtifactHandle, error) {
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+
+	unlock, err := r.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	key := artifactKey(name, platform, sourceHash)
+
+	var rec artifactRecord
+	found := false
+	err = r.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(artifactBucket).Get(key)
+		if v == nil {
+			return nil
+		}
+		found = true
+		return json.Unmarshal(v, &rec)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if found && sourceHash != "" {
+		return &ArtifactHandle{Digest: rec.Digest, resolver: r}, nil
+	}
+
+	scanPath, err := scanBinPrefixes(name, platform)
+	if err != nil {
+		return nil, err
+	}
+	digest, err := r.importBlob(scanPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if sourceHash == "" {
+		return &ArtifactHandle{Digest: digest, resolver: r}, nil
+	}
+	rec = artifactRecord{Digest: digest, MTime: time.Now()}
+	encoded, err := json.Marshal(rec)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(artifactBucket).Put(key, encoded)
+	}); err != nil {
+		return nil, err
+	}
+
+	return &ArtifactHandle{Digest: digest, resolver: r}, nil
+}
+
+func (r *ArtifactResolver) blobPath(digest string) string {
+	return filepath.Join(r.CacheDir, "sha256", digest)
+}
+
+// importBlob copies srcPath into the content store under its sha256 digest,
+// preserving srcPath's file mode (findBinary uses this to materialize
+// executables, which must keep their executable bit), and returns that
+// digest.
+func (r *ArtifactResolver) importBlob(srcPath string) (string, error) {
+	srcInfo, err := os.Stat(srcPath)
+	if err != nil {
+		return "", err
+	}
+	src, err := os.Open(srcPath)
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+
+	h := sha256.New()
+	tmp, err := ioutil.TempFile(r.CacheDir, "blob-")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	if _, err := io.Copy(io.MultiWriter(tmp, h), src); err != nil {
+		return "", err
+	}
+	digest := hex.EncodeToString(h.Sum(nil))
+
+	if err := tmp.Chmod(srcInfo.Mode()); err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(r.blobPath(digest)), 0755); err != nil {
+		return "", err
+	}
+	tmp.Close()
+	if err := os.Rename(tmp.Name(), r.blobPath(digest)); err != nil {
+		return "", err
+	}
+	return digest, nil
+}
+
+// materialize returns the local path for digest, importing it through the
+// configured CacheImporter if it is not already present in the content
+// store (e.g. evicted, or never populated locally because it was only ever
+// recorded by another parallel test node).
+func (r *ArtifactResolver) materialize(digest string) (string, error) {
+	blobPath := r.blobPath(digest)
+	if _, err := os.Stat(blobPath); err == nil {
+		return blobPath, nil
+	}
+	if r.Importer == nil {
+		return "", fmt.Errorf("artifact cache: blob %s missing locally and no CacheImporter is configured", digest)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(blobPath), 0755); err != nil {
+		return "", err
+	}
+	tmp, err := ioutil.TempFile(r.CacheDir, "import-")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	if err := r.Importer.Import(digest, tmp); err != nil {
+		return "", fmt.Errorf("artifact cache: importing %s: %v", digest, err)
+	}
+	tmp.Close()
+	if err := os.Rename(tmp.Name(), blobPath); err != nil {
+		return "", err
+	}
+	return blobPath, nil
+}
+
+// lock serializes Resolve across processes (parallel Ginkgo nodes sharing
+// CacheDir) using a flock'd lockfile, since boltdb alone only guards
+// concurrent access within a single process that opened the db.
+func (r *ArtifactResolver) lock() (unlock func(), err error) {
+	f, err := os.OpenFile(filepath.Join(r.CacheDir, ".lock"), os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return func() {
+		syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		f.Close()
+	}, nil
+}
+
+func artifactKey(name, platform, sourceHash string) []byte {
+	return []byte(path.Join(name, platform, sourceHash))
+}
+
+// scanBinPrefixes reproduces findBinary's original mtime-scan behavior: it
+// is only consulted on an ArtifactResolver cache miss, to locate and index
+// a binary that hasn't been resolved before.
+func scanBinPrefixes(binName, platform string) (string, error) {
+	var binTime time.Time
+	var binPath string
+
+	for _, pre := range binPrefixes {
+		tryPath := path.Join(TestContext.RepoRoot, pre, platform, binName)
+		fi, err := os.Stat(tryPath)
+		if err != nil {
+			continue
+		}
+		if fi.ModTime().After(binTime) {
+			binPath = tryPath
+			binTime = fi.ModTime()
+		}
+	}
+
+	if len(binPath) > 0 {
+		return binPath, nil
+	}
+	return "", fmt.Errorf("could not find %v for %v", binName, platform)
+}
@@ -0,0 +1,218 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Event is one entry in an EventBus, modeled on the shape of a line from
+// Docker/Podman's GET /events: a typed, timestamped record of something a
+// validator observed, meant to replace an ad-hoc framework.Logf call so
+// that CI dashboards and test triage tools can consume test progress as
+// structured data instead of grepping log text.
+type Event struct {
+	Time       time.Time         `json:"time"`
+	Test       string            `json:"test"`
+	Kind       string            `json:"kind"`
+	Resource   string            `json:"resource"`
+	Namespace  string            `json:"namespace"`
+	Message    string            `json:"message"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// EventFilter matches Docker's filter grammar: a set of field name to
+// accepted-values lists, ANDed together, with an event matching a field if
+// it equals any one of that field's values ("label" matches against
+// Attributes instead of a fixed struct field).
+type EventFilter map[string][]string
+
+// Matches reports whether e satisfies every field in f.
+func (f EventFilter) Matches(e Event) bool {
+	for field, values := range f {
+		if !matchesField(e, field, values) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchesField(e Event, field string, values []string) bool {
+	if field == "label" {
+		for _, v := range values {
+			k, want := splitLabelFilter(v)
+			if got, ok := e.Attributes[k]; ok && (want == "" || got == want) {
+				return true
+			}
+		}
+		return false
+	}
+
+	var actual string
+	switch field {
+	case "kind":
+		actual = e.Kind
+	case "resource":
+		actual = e.Resource
+	case "namespace":
+		actual = e.Namespace
+	case "test":
+		actual = e.Test
+	default:
+		return false
+	}
+	for _, v := range values {
+		if actual == v {
+			return true
+		}
+	}
+	return false
+}
+
+func splitLabelFilter(v string) (key, value string) {
+	parts := strings.SplitN(v, "=", 2)
+	if len(parts) == 2 {
+		return parts[0], parts[1]
+	}
+	return parts[0], ""
+}
+
+// EventSummary buckets one test's published events by kind, for a
+// post-run summary of what each test observed.
+type EventSummary struct {
+	Test   string         `json:"test"`
+	Counts map[string]int `json:"counts"`
+}
+
+// EventBus is a small in-process event log: validators Publish to it
+// instead of calling framework.Logf, and CI tooling queries it (directly,
+// or over HTTP via ServeHTTP) instead of scraping log output.
+type EventBus struct {
+	// Out, if non-nil, receives each published Event as a JSON line, the
+	// same shape docker events --format '{{json .}}' produces.
+	Out io.Writer
+
+	mu     sync.Mutex
+	events []Event
+}
+
+// NewEventBus returns an EventBus that additionally writes each event as a
+// JSON line to out. out may be nil to only keep events in memory.
+func NewEventBus(out io.Writer) *EventBus {
+	return &EventBus{Out: out}
+}
+
+// Publish records e, stamping Time with the current time if it is zero.
+func (b *EventBus) Publish(e Event) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	b.mu.Lock()
+	b.events = append(b.events, e)
+	b.mu.Unlock()
+
+	if b.Out != nil {
+		if data, err := json.Marshal(e); err == nil {
+			b.Out.Write(append(data, '\n'))
+		}
+	}
+}
+
+// Query returns every published event at or after since that matches
+// filter, in publication order.
+func (b *EventBus) Query(filter EventFilter, since time.Time) []Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var matched []Event
+	for _, e := range b.events {
+		if e.Time.Before(since) {
+			continue
+		}
+		if filter.Matches(e) {
+			matched = append(matched, e)
+		}
+	}
+	return matched
+}
+
+// Summarize buckets every published event by test name and kind, for a
+// post-run summary of what each test observed.
+func (b *EventBus) Summarize() []EventSummary {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	order := []string{}
+	byTest := map[string]*EventSummary{}
+	for _, e := range b.events {
+		s, ok := byTest[e.Test]
+		if !ok {
+			s = &EventSummary{Test: e.Test, Counts: map[string]int{}}
+			byTest[e.Test] = s
+			order = append(order, e.Test)
+		}
+		s.Counts[e.Kind]++
+	}
+
+	summaries := make([]EventSummary, 0, len(order))
+	for _, t := range order {
+		summaries = append(summaries, *byTest[t])
+	}
+	return summaries
+}
+
+// ServeHTTP implements "GET /events?filter=kind=validation,namespace=foo&since=<RFC3339>",
+// a query-string rendering of Docker's filter grammar, returning matching
+// events as a JSON array. An empty or absent filter/since matches
+// everything published so far.
+func (b *EventBus) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	since := time.Time{}
+	if s := r.URL.Query().Get("since"); s != "" {
+		parsed, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			http.Error(w, "invalid since: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		since = parsed
+	}
+
+	filter := EventFilter{}
+	if f := r.URL.Query().Get("filter"); f != "" {
+		for _, clause := range strings.Split(f, ",") {
+			k, v := splitLabelFilter(clause)
+			filter[k] = append(filter[k], v)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(b.Query(filter, since))
+}
+
+// DefaultEventBus is the EventBus package-level validators publish to when
+// they don't have a more specific bus threaded through.
+var DefaultEventBus = NewEventBus(nil)
+
+// PublishEvent publishes e to DefaultEventBus.
+func PublishEvent(e Event) {
+	DefaultEventBus.Publish(e)
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"fmt"
+	"time"
+
+	"golang.org/x/net/context"
+
+	apierrs "k8s.io/kubernetes/pkg/api/errors"
+	"k8s.io/kubernetes/pkg/api/meta"
+	"k8s.io/kubernetes/pkg/runtime"
+)
+
+// GuardedUpdateClient is the minimal per-resource client surface
+// GuardedUpdate needs: re-read the current object, and post an updated one.
+// Every client.Client resource interface (ReplicationControllers, Pods,
+// ...) already exposes Get(name)/Update(obj); callers adapt theirs to this
+// with a small closure-based wrapper.
+type GuardedUpdateClient interface {
+	Get() (runtime.Object, error)
+	Update(obj runtime.Object) (runtime.Object, error)
+}
+
+// GuardedUpdate performs an etcd3-store-style guarded read-modify-write: it
+// applies mutate to obj and calls client.Update, and on a 409 conflict
+// re-reads the current object via client.Get, re-applies mutate to that
+// fresh copy, and retries with bounded backoff. This replaces read-mutate-
+// repost helpers like modifyReplicationControllerConfiguration, which apply
+// a mutation in memory and post it with no conflict detection at all, so a
+// concurrent rewrite from controller-manager or another test silently loses
+// an update.
+//
+// origStateIsCurrent tells GuardedUpdate whether obj is already known to be
+// the current state (e.g. the caller just read it moments ago). If the
+// very first Update attempt still conflicts, a normal refresh-and-retry
+// resolves it. But if a copy GuardedUpdate itself just re-read conflicts
+// again at the exact resource version it already retried against, looping
+// won't fix that -- it means the apiserver handed back the same stale
+// version twice -- so GuardedUpdate fails fast instead of retrying forever.
+func GuardedUpdate(ctx context.Context, client GuardedUpdateClient, obj runtime.Object, origStateIsCurrent bool, mutate func(runtime.Object) (runtime.Object, error)) (runtime.Object, error) {
+	backoff := NewExponentialBackoff()
+	current := obj
+	knownCurrent := origStateIsCurrent
+	var lastConflictVersion string
+
+	for {
+		mutated, err := mutate(current)
+		if err != nil {
+			return nil, err
+		}
+
+		updated, err := client.Update(mutated)
+		if err == nil {
+			return updated, nil
+		}
+		if !apierrs.IsConflict(err) {
+			return nil, err
+		}
+
+		accessor, accErr := meta.Accessor(mutated)
+		if accErr != nil {
+			return nil, err
+		}
+		version := accessor.GetResourceVersion()
+		if knownCurrent && version == lastConflictVersion {
+			return nil, fmt.Errorf("guarded update: repeated conflict at resource version %s with no intervening refresh: %v", version, err)
+		}
+		lastConflictVersion = version
+		knownCurrent = false
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(backoff.Next()):
+		}
+
+		refreshed, getErr := client.Get()
+		if getErr != nil {
+			return nil, getErr
+		}
+		current = refreshed
+		knownCurrent = true
+	}
+}
@@ -0,0 +1,256 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"fmt"
+	"io"
+	"os/exec"
+	"sync"
+
+	client "k8s.io/kubernetes/pkg/client/unversioned"
+)
+
+// KubectlRunner abstracts how a kubectl invocation is actually executed so
+// that e2e tests can be written once and run against kubectl in-process on
+// the test host, execed into a pod already running in the cluster, or run
+// over SSH on the master -- without special-casing providers at the call
+// site. Implementations must expose stdin/stdout/stderr as streams that are
+// usable while the command is still running, not only after it completes,
+// so callers can drive interactive or long-running commands like
+// "kubectl logs -f" or "kubectl port-forward".
+type KubectlRunner interface {
+	// Start begins running kubectl with the given arguments under env (nil
+	// or empty means "inherit the runner's own default environment"). The
+	// returned stdin may be written to (and must be closed by the caller
+	// when done); stdout and stderr may be read from as the command produces
+	// output. wait blocks until the command exits and returns its result.
+	Start(env []string, args ...string) (stdin io.WriteCloser, stdout, stderr io.Reader, wait func() error, err error)
+}
+
+// LocalKubectlRunner runs kubectl as a local subprocess on the machine
+// executing the test suite. This is the historical behavior of
+// framework.RunKubectl and friends.
+type LocalKubectlRunner struct{}
+
+// NewLocalKubectlRunner returns a KubectlRunner that execs the kubectl
+// binary configured for this test run as a local subprocess.
+func NewLocalKubectlRunner() *LocalKubectlRunner {
+	return &LocalKubectlRunner{}
+}
+
+func (r *LocalKubectlRunner) Start(env []string, args ...string) (io.WriteCloser, io.Reader, io.Reader, func() error, error) {
+	cmd := KubectlCmd(args...)
+	if len(env) > 0 {
+		cmd.Env = env
+	}
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	stdout, stderr, err := StartCmdAndStreamOutput(cmd)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	wait := func() error {
+		defer stdout.Close()
+		defer stderr.Close()
+		return cmd.Wait()
+	}
+	return stdin, stdout, stderr, wait, nil
+}
+
+// inPodKubectlPath is where NewInClusterKubectlRunner copies the local
+// kubectl binary to inside the target pod. The plain test images this is
+// used against (e.g. "nginx") have no kubectl of their own to exec.
+const inPodKubectlPath = "/tmp/e2e-kubectl"
+
+// InClusterKubectlRunner runs kubectl from inside a pod already scheduled on
+// the cluster under test, by execing into it via the apiserver's exec
+// subresource. This is used by providers where the test host cannot reach
+// the apiserver directly but a pod on the cluster can.
+type InClusterKubectlRunner struct {
+	Client    *client.Client
+	Namespace string
+	PodName   string
+	Container string
+
+	bootstrapOnce sync.Once
+	bootstrapErr  error
+}
+
+// NewInClusterKubectlRunner returns a KubectlRunner that runs kubectl inside
+// an already-running pod via "kubectl exec" semantics.
+func NewInClusterKubectlRunner(c *client.Client, namespace, podName, container string) *InClusterKubectlRunner {
+	return &InClusterKubectlRunner{Client: c, Namespace: namespace, PodName: podName, Container: container}
+}
+
+// ensureKubectlCopied copies this test run's local kubectl binary into the
+// target pod the first time the runner is used, since the pod's own image
+// (e.g. plain "nginx") has no kubectl of its own. The copy is always done
+// via an explicit LocalKubectlRunner rather than framework.RunKubectl, since
+// by the time a caller is routing through an InClusterKubectlRunner it is
+// typically also the DefaultKubectlRunner, and going through the package
+// level helpers here would exec back into the very pod being bootstrapped.
+func (r *InClusterKubectlRunner) ensureKubectlCopied() error {
+	r.bootstrapOnce.Do(func() {
+		local := &LocalKubectlRunner{}
+		dest := fmt.Sprintf("%s/%s:%s", r.Namespace, r.PodName, inPodKubectlPath)
+		if _, err := RunKubectlViaRunner(local, nil, "cp", TestContext.KubectlPath, dest, "-c", r.Container); err != nil {
+			r.bootstrapErr = fmt.Errorf("copying kubectl into pod %s/%s: %v", r.Namespace, r.PodName, err)
+			return
+		}
+		if _, err := RunKubectlViaRunner(local, nil, "exec", "-n", r.Namespace, r.PodName, "-c", r.Container, "--", "chmod", "+x", inPodKubectlPath); err != nil {
+			r.bootstrapErr = fmt.Errorf("marking in-pod kubectl executable in %s/%s: %v", r.Namespace, r.PodName, err)
+		}
+	})
+	return r.bootstrapErr
+}
+
+func (r *InClusterKubectlRunner) Start(env []string, args ...string) (io.WriteCloser, io.Reader, io.Reader, func() error, error) {
+	if err := r.ensureKubectlCopied(); err != nil {
+		return nil, nil, nil, nil, err
+	}
+
+	execArgs := []string{"exec", "-n", r.Namespace, "-i", r.PodName, "-c", r.Container, "--"}
+	// The env the caller wants (e.g. HTTPS_PROXY for the exec proxy tests)
+	// has to reach the kubectl running *inside* the pod, not the outer
+	// "kubectl exec" process connecting to it, so forward it ahead of the
+	// in-pod binary via the coreutils "env" command rather than setting it
+	// on the outer local command.
+	if len(env) > 0 {
+		execArgs = append(execArgs, "env")
+		execArgs = append(execArgs, env...)
+	}
+	execArgs = append(execArgs, inPodKubectlPath)
+	execArgs = append(execArgs, args...)
+
+	local := &LocalKubectlRunner{}
+	return local.Start(nil, execArgs...)
+}
+
+// SSHKubectlRunner runs kubectl over SSH to the cluster master, for
+// providers where kubectl must run on the master rather than the test host.
+type SSHKubectlRunner struct {
+	Host string
+}
+
+// NewSSHKubectlRunner returns a KubectlRunner that runs kubectl on the given
+// host over SSH.
+func NewSSHKubectlRunner(host string) *SSHKubectlRunner {
+	return &SSHKubectlRunner{Host: host}
+}
+
+func (r *SSHKubectlRunner) Start(env []string, args ...string) (io.WriteCloser, io.Reader, io.Reader, func() error, error) {
+	// SSH doesn't give us a live stdin/stdout pair the way a local exec.Cmd
+	// does without going through the same plumbing, so shell out to the
+	// system ssh client and reuse the local runner's streaming behavior.
+	sshArgs := []string{r.Host}
+	// As with InClusterKubectlRunner, env has to land on the remote
+	// "kubectl" process, not the local ssh client, so forward it through
+	// the remote shell's own env command.
+	if len(env) > 0 {
+		sshArgs = append(sshArgs, "env")
+		sshArgs = append(sshArgs, env...)
+	}
+	sshArgs = append(sshArgs, "kubectl")
+	sshArgs = append(sshArgs, args...)
+	cmd := exec.Command("ssh", sshArgs...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	stdout, stderr, err := StartCmdAndStreamOutput(cmd)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	wait := func() error {
+		defer stdout.Close()
+		defer stderr.Close()
+		return cmd.Wait()
+	}
+	return stdin, stdout, stderr, wait, nil
+}
+
+// defaultKubectlRunner is the runner used by RunKubectl, NewKubectlCommand,
+// and friends when no runner has been explicitly selected. Providers that
+// cannot exec kubectl locally against the apiserver (e.g. because only an
+// in-cluster pod or the master has network access to it) should call
+// SetDefaultKubectlRunner during test setup instead of sprinkling
+// SkipIfProviderIs("local")-style skips through individual tests.
+var defaultKubectlRunner KubectlRunner = NewLocalKubectlRunner()
+
+// SetDefaultKubectlRunner overrides the KubectlRunner used by the package
+// level RunKubectl/NewKubectlCommand helpers.
+func SetDefaultKubectlRunner(r KubectlRunner) {
+	if r == nil {
+		r = NewLocalKubectlRunner()
+	}
+	defaultKubectlRunner = r
+}
+
+// DefaultKubectlRunner returns the KubectlRunner currently in effect.
+func DefaultKubectlRunner() KubectlRunner {
+	return defaultKubectlRunner
+}
+
+// RunKubectlViaRunner runs kubectl to completion using the given runner and
+// returns its combined stdout. It is the streaming-capable analogue of
+// RunKubectl for callers that need to select a specific runner (e.g. to
+// replace a SkipIfProviderIs("local") guard with an in-cluster or SSH
+// runner instead of skipping the test outright).
+func RunKubectlViaRunner(r KubectlRunner, env []string, args ...string) (string, error) {
+	stdin, stdout, stderr, wait, err := r.Start(env, args...)
+	if err != nil {
+		return "", err
+	}
+	stdin.Close()
+	var out, errOut []byte
+	outCh := make(chan []byte, 1)
+	errCh := make(chan []byte, 1)
+	go func() {
+		b, _ := readAll(stdout)
+		outCh <- b
+	}()
+	go func() {
+		b, _ := readAll(stderr)
+		errCh <- b
+	}()
+	out = <-outCh
+	errOut = <-errCh
+	if waitErr := wait(); waitErr != nil {
+		return string(out), fmt.Errorf("error running kubectl %v: %v (stderr: %s)", args, waitErr, errOut)
+	}
+	return string(out), nil
+}
+
+func readAll(r io.Reader) ([]byte, error) {
+	buf := make([]byte, 0, 4096)
+	chunk := make([]byte, 4096)
+	for {
+		n, err := r.Read(chunk)
+		if n > 0 {
+			buf = append(buf, chunk[:n]...)
+		}
+		if err != nil {
+			if err == io.EOF {
+				return buf, nil
+			}
+			return buf, err
+		}
+	}
+}
@@ -0,0 +1,110 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// PrivateRegistryContext holds the flags needed to drive the private-registry
+// image build/push/pull e2e coverage. It's populated from flags registered
+// alongside the rest of TestContext, following the "vkube" v23 harness's
+// --project/--zone/--registry parameterization.
+type PrivateRegistryContext struct {
+	// Registry is the host[:port] of a private Docker registry reachable
+	// from both the test host and the cluster under test.
+	Registry string
+	// RegistrySecretFile points at a dockercfg/dockerconfigjson file with
+	// credentials for Registry.
+	RegistrySecretFile string
+	// RebuildProbability is the chance, in [0,1], that a given test run
+	// rebuilds and re-pushes the test image instead of reusing the cached
+	// tag. Low by default so CI mostly exercises the fast/cached path, but
+	// occasionally forces a rebuild to catch registry-auth regressions.
+	RebuildProbability float64
+}
+
+// PrivateRegistryTestContext is the package-level instance populated by the
+// flags registered in this file's init.
+var PrivateRegistryTestContext PrivateRegistryContext
+
+func init() {
+	flag.StringVar(&PrivateRegistryTestContext.Registry, "docker-registry", "", "host[:port] of a private docker registry the e2e run should push/pull a test image through")
+	flag.StringVar(&PrivateRegistryTestContext.RegistrySecretFile, "docker-registry-secret", "", "path to a dockerconfigjson credentials file for --docker-registry")
+	flag.Float64Var(&PrivateRegistryTestContext.RebuildProbability, "rebuild-probability", 0.05, "probability that the private-registry e2e test rebuilds its test image instead of reusing a cached tag")
+}
+
+// PrivateRegistryConfigured reports whether enough flags were provided to run
+// the private-registry pull-path e2e coverage.
+func PrivateRegistryConfigured() bool {
+	return PrivateRegistryTestContext.Registry != "" && PrivateRegistryTestContext.RegistrySecretFile != ""
+}
+
+const privateRegistryTestDockerfile = `FROM gcr.io/google_containers/busybox:1.24
+CMD ["sh", "-c", "echo e2e-private-registry-marker && sleep 3600"]
+`
+
+// BuildAndPushPrivateRegistryTestImage builds a tiny marker image from an
+// inlined Dockerfile and pushes it to PrivateRegistryTestContext.Registry
+// under the given tag, unless rand.Float64() lands outside
+// RebuildProbability and tag already exists remotely -- in which case the
+// cached tag is reused. It returns the fully qualified image reference.
+func BuildAndPushPrivateRegistryTestImage(tag string) (string, error) {
+	image := fmt.Sprintf("%s/e2e-private-registry-test:%s", PrivateRegistryTestContext.Registry, tag)
+
+	if rand.Float64() > PrivateRegistryTestContext.RebuildProbability {
+		if imageExistsRemotely(image) {
+			Logf("Reusing cached private-registry test image %s", image)
+			return image, nil
+		}
+	}
+
+	tmpdir, err := ioutil.TempDir("", "kubectl-private-registry-test")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(tmpdir)
+
+	dockerfilePath := filepath.Join(tmpdir, "Dockerfile")
+	if err := ioutil.WriteFile(dockerfilePath, []byte(privateRegistryTestDockerfile), 0644); err != nil {
+		return "", err
+	}
+
+	Logf("Building private-registry test image %s", image)
+	if out, err := exec.Command("docker", "build", "-t", image, tmpdir).CombinedOutput(); err != nil {
+		return "", fmt.Errorf("docker build failed: %v\n%s", err, out)
+	}
+	if out, err := exec.Command("docker", "push", image).CombinedOutput(); err != nil {
+		return "", fmt.Errorf("docker push failed: %v\n%s", err, out)
+	}
+	return image, nil
+}
+
+func imageExistsRemotely(image string) bool {
+	// "docker manifest inspect" (or, pre-CLI-support, a plain re-pull) is
+	// the only portable way to ask a registry whether a tag exists without
+	// additional registry-specific tooling; a failure here just means we
+	// fall back to rebuilding, which is always safe.
+	err := exec.Command("docker", "pull", image).Run()
+	return err == nil
+}
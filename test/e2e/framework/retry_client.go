@@ -0,0 +1,149 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"math"
+	"math/rand"
+	"net"
+	"strings"
+	"time"
+
+	"golang.org/x/net/context"
+
+	apierrs "k8s.io/kubernetes/pkg/api/errors"
+)
+
+// ExponentialBackoff produces successive retry intervals the way
+// cenkalti/backoff's ExponentialBackOff does: each interval is the previous
+// one multiplied by Multiplier, capped at MaxInterval, then randomized by
+// +/- RandomizationFactor so that many callers retrying in lockstep don't
+// all hammer the apiserver on the same tick.
+type ExponentialBackoff struct {
+	InitialInterval     time.Duration
+	Multiplier          float64
+	MaxInterval         time.Duration
+	RandomizationFactor float64
+
+	current time.Duration
+}
+
+// NewExponentialBackoff returns a backoff with the defaults this package
+// uses everywhere: 500ms initial interval, 1.5x multiplier, 30s cap, and
+// 0.5 randomization factor.
+func NewExponentialBackoff() *ExponentialBackoff {
+	return &ExponentialBackoff{
+		InitialInterval:     500 * time.Millisecond,
+		Multiplier:          1.5,
+		MaxInterval:         30 * time.Second,
+		RandomizationFactor: 0.5,
+	}
+}
+
+// Next returns the next retry interval, advancing the backoff's internal
+// state.
+func (b *ExponentialBackoff) Next() time.Duration {
+	if b.current == 0 {
+		b.current = b.InitialInterval
+	} else {
+		b.current = time.Duration(math.Min(float64(b.MaxInterval), float64(b.current)*b.Multiplier))
+	}
+	return jitter(b.current, b.RandomizationFactor)
+}
+
+func jitter(d time.Duration, factor float64) time.Duration {
+	if factor <= 0 {
+		return d
+	}
+	delta := factor * float64(d)
+	min := float64(d) - delta
+	max := float64(d) + delta
+	return time.Duration(min + rand.Float64()*(max-min))
+}
+
+// IsRetryableError classifies an error returned from a Kubernetes client
+// call as retryable (HTTP 5xx equivalents and connection resets) or
+// terminal (HTTP 4xx equivalents and permanent transport errors like TLS
+// verification failures).
+func IsRetryableError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if apierrs.IsServerTimeout(err) || apierrs.IsInternalError(err) || apierrs.IsTimeout(err) || apierrs.IsServiceUnavailable(err) {
+		return true
+	}
+	if netErr, ok := err.(net.Error); ok && (netErr.Temporary() || netErr.Timeout()) {
+		return true
+	}
+	if strings.Contains(err.Error(), "connection reset by peer") {
+		return true
+	}
+	return false
+}
+
+// RetryClient retries a Kubernetes client call with exponential backoff and
+// jitter while the call keeps failing with a retryable error, replacing the
+// fixed 5-second sleep loops the kubectl e2e tests used to hand-roll around
+// waitForGuestbookResponse and the proxy helpers. Those loops had no
+// backoff and no way to tell a transient 5xx from a permanent failure, so a
+// flaky connection reset looked identical to a genuinely broken app.
+type RetryClient struct {
+	// ShouldRetry classifies an error as retryable. Defaults to
+	// IsRetryableError.
+	ShouldRetry func(err error) bool
+	// NewBackoff returns a fresh backoff for each Do call. Defaults to
+	// NewExponentialBackoff.
+	NewBackoff func() *ExponentialBackoff
+}
+
+// NewRetryClient returns a RetryClient configured with this package's
+// default retry policy and backoff parameters.
+func NewRetryClient() *RetryClient {
+	return &RetryClient{
+		ShouldRetry: IsRetryableError,
+		NewBackoff:  NewExponentialBackoff,
+	}
+}
+
+// Do calls fn, retrying with backoff and jitter as long as fn returns a
+// retryable error and ctx has not been cancelled or exceeded its deadline.
+// It returns fn's last result once fn succeeds, returns a terminal error, or
+// ctx is done -- whichever comes first.
+func (r *RetryClient) Do(ctx context.Context, fn func() ([]byte, error)) ([]byte, error) {
+	shouldRetry := r.ShouldRetry
+	if shouldRetry == nil {
+		shouldRetry = IsRetryableError
+	}
+	newBackoff := r.NewBackoff
+	if newBackoff == nil {
+		newBackoff = NewExponentialBackoff
+	}
+
+	backoff := newBackoff()
+	for {
+		result, err := fn()
+		if err == nil || !shouldRetry(err) {
+			return result, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return result, err
+		case <-time.After(backoff.Next()):
+		}
+	}
+}
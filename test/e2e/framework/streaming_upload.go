@@ -0,0 +1,264 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+const (
+	defaultUploadChunkSize  = 4 * 1024 * 1024
+	defaultUploadIdleTimout = 30 * time.Second
+	maxChunkAttempts        = 3
+)
+
+// UploadProgress reports how much of a StreamingUploader's upload has been
+// sent so far, delivered on StreamingUploader.Progress as each chunk
+// completes.
+type UploadProgress struct {
+	Part       int
+	BytesSent  int64
+	TotalBytes int64
+}
+
+// StreamingUploader streams a multipart file upload to a URL in fixed-size
+// chunks, resuming a stalled or rejected chunk with a Content-Range request
+// instead of restarting the whole upload. It replaces the old
+// newStreamingUpload/streamingUpload pair, which copied an entire file into
+// a multipart.Writer on one goroutine with no retry, no progress reporting,
+// and no way to abort a stuck upload -- the kind of upload that flaked on
+// large kubectl binaries in CI.
+//
+// An idle timer (modeled on Podman's idle.Tracker) aborts the upload if no
+// bytes are read from the source for IdleTimeout, so a stuck exec-proxy
+// upload can't leak the goroutine driving it forever.
+type StreamingUploader struct {
+	URL       string
+	FieldName string
+	FileName  string
+
+	// ChunkSize is the number of bytes uploaded per request. Defaults to
+	// 4MiB if zero.
+	ChunkSize int64
+	// IdleTimeout aborts the upload if no bytes are read from the source
+	// reader for this long. Defaults to 30s if zero.
+	IdleTimeout time.Duration
+	// Client performs the chunk requests. Defaults to http.DefaultClient.
+	Client *http.Client
+	// Progress, if non-nil, receives an UploadProgress after each chunk
+	// that is accepted by the server.
+	Progress chan<- UploadProgress
+}
+
+// NewStreamingUploader returns a StreamingUploader posting fieldName/fileName
+// as a multipart form file to url, with the package defaults for chunk size,
+// idle timeout, and HTTP client.
+func NewStreamingUploader(url, fieldName, fileName string) *StreamingUploader {
+	return &StreamingUploader{
+		URL:       url,
+		FieldName: fieldName,
+		FileName:  fileName,
+	}
+}
+
+// Upload streams size bytes from r to the uploader's URL, split into
+// ChunkSize pieces. Each chunk is sent with a Content-Range header and
+// attempted up to maxChunkAttempts times in all, retrying on a transport
+// error or a 5xx response, before Upload gives up and returns an error. If
+// ctx is cancelled, including when the idle timer fires, Upload returns an
+// error that is or wraps the context's error.
+func (u *StreamingUploader) Upload(ctx context.Context, r io.Reader, size int64) error {
+	chunkSize := u.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = defaultUploadChunkSize
+	}
+	idleTimeout := u.IdleTimeout
+	if idleTimeout <= 0 {
+		idleTimeout = defaultUploadIdleTimout
+	}
+	client := u.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	tracked := newIdleTrackingReader(ctx, r, idleTimeout)
+	defer tracked.Stop()
+
+	var sent int64
+	for part := 0; sent < size; part++ {
+		n := chunkSize
+		if remaining := size - sent; remaining < n {
+			n = remaining
+		}
+		chunk := make([]byte, n)
+		if _, err := io.ReadFull(tracked, chunk); err != nil {
+			return fmt.Errorf("streaming upload: reading part %d: %v", part, err)
+		}
+
+		if err := u.uploadChunkWithRetry(ctx, client, chunk, sent, size); err != nil {
+			return err
+		}
+
+		sent += n
+		if u.Progress != nil {
+			u.Progress <- UploadProgress{Part: part, BytesSent: sent, TotalBytes: size}
+		}
+	}
+	return nil
+}
+
+func (u *StreamingUploader) uploadChunkWithRetry(ctx context.Context, client *http.Client, chunk []byte, offset, total int64) error {
+	digest := sha256.Sum256(chunk)
+
+	var lastErr error
+	for attempt := 0; attempt < maxChunkAttempts; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(backoffForAttempt(attempt)):
+			}
+		}
+
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		fw, err := mw.CreateFormFile(u.FieldName, u.FileName)
+		if err != nil {
+			return fmt.Errorf("streaming upload: building form part: %v", err)
+		}
+		if _, err := fw.Write(chunk); err != nil {
+			return fmt.Errorf("streaming upload: writing form part: %v", err)
+		}
+		if err := mw.Close(); err != nil {
+			return fmt.Errorf("streaming upload: closing form part: %v", err)
+		}
+
+		req, err := http.NewRequest("POST", u.URL, &body)
+		if err != nil {
+			return fmt.Errorf("streaming upload: building request: %v", err)
+		}
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		req.Header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", offset, offset+int64(len(chunk))-1, total))
+		req.Header.Set("X-Chunk-SHA256", fmt.Sprintf("%x", digest))
+		req = req.WithContext(ctx)
+
+		resp, err := client.Do(req)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 500 {
+			lastErr = fmt.Errorf("streaming upload: server returned %s for bytes %d-%d", resp.Status, offset, offset+int64(len(chunk))-1)
+			continue
+		}
+		if resp.StatusCode >= 400 {
+			return fmt.Errorf("streaming upload: server rejected bytes %d-%d: %s", offset, offset+int64(len(chunk))-1, resp.Status)
+		}
+		return nil
+	}
+	return fmt.Errorf("streaming upload: giving up after %d attempts: %v", maxChunkAttempts, lastErr)
+}
+
+func backoffForAttempt(attempt int) time.Duration {
+	return time.Duration(attempt) * 500 * time.Millisecond
+}
+
+// idleTrackingReader wraps an io.Reader and cancels its context if a Read
+// doesn't return within idleTimeout of the previous one, analogous to
+// Podman's idle.Tracker guarding a stuck container attach stream.
+type idleTrackingReader struct {
+	r       io.Reader
+	timer   *time.Timer
+	cancel  context.CancelFunc
+	timeout time.Duration
+}
+
+func newIdleTrackingReader(ctx context.Context, r io.Reader, timeout time.Duration) *idleTrackingReader {
+	ctx, cancel := context.WithCancel(ctx)
+	t := &idleTrackingReader{r: r, cancel: cancel, timeout: timeout}
+	t.timer = time.AfterFunc(timeout, cancel)
+	t.r = readerWithContext(ctx, r)
+	return t
+}
+
+func (t *idleTrackingReader) Read(p []byte) (int, error) {
+	n, err := t.r.Read(p)
+	if n > 0 {
+		t.timer.Reset(t.timeout)
+	}
+	return n, err
+}
+
+func (t *idleTrackingReader) Stop() {
+	t.timer.Stop()
+	t.cancel()
+}
+
+// readerWithContext returns a reader that fails with ctx.Err() once ctx is
+// done, so a Read blocked on a stalled upstream source doesn't hang forever
+// once the idle timer (or an outer cancellation) fires.
+func readerWithContext(ctx context.Context, r io.Reader) io.Reader {
+	return &ctxReader{ctx: ctx, r: r}
+}
+
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+type ctxReadResult struct {
+	n   int
+	err error
+}
+
+// Read races the underlying Read against ctx.Done(), so a source that's
+// gone quiet mid-Read (the stuck exec-proxy upload this package exists to
+// guard against) is abandoned as soon as the idle timer fires instead of
+// only being checked before a Read that then blocks forever. The losing
+// Read, if any, is left running in its own goroutine until it eventually
+// returns on its own -- there's no portable way to force an arbitrary
+// io.Reader to give up a blocked Read, short of requiring it implement
+// something like SetReadDeadline.
+func (c *ctxReader) Read(p []byte) (int, error) {
+	select {
+	case <-c.ctx.Done():
+		return 0, c.ctx.Err()
+	default:
+	}
+
+	resultCh := make(chan ctxReadResult, 1)
+	go func() {
+		n, err := c.r.Read(p)
+		resultCh <- ctxReadResult{n, err}
+	}()
+
+	select {
+	case <-c.ctx.Done():
+		return 0, c.ctx.Err()
+	case res := <-resultCh:
+		return res.n, res.err
+	}
+}
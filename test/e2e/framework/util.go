@@ -0,0 +1,195 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// KubectlCmd prepares a local "kubectl <args>" *exec.Cmd against this test
+// run's configured binary, server and kubeconfig. It is used directly by
+// callers that need raw control of the process (port-forward, proxy,
+// "logs -f") rather than the buffered request/response helpers below.
+func KubectlCmd(args ...string) *exec.Cmd {
+	defaultArgs := []string{}
+	if TestContext.KubeConfig != "" {
+		defaultArgs = append(defaultArgs, fmt.Sprintf("--kubeconfig=%s", TestContext.KubeConfig))
+	}
+	if TestContext.Host != "" {
+		defaultArgs = append(defaultArgs, fmt.Sprintf("--server=%s", TestContext.Host))
+	}
+	kubectlArgs := append(defaultArgs, args...)
+	return exec.Command(TestContext.KubectlPath, kubectlArgs...)
+}
+
+// StartCmdAndStreamOutput starts cmd and returns pipes for its stdout and
+// stderr that are readable while the command is still running.
+func StartCmdAndStreamOutput(cmd *exec.Cmd) (stdout, stderr io.ReadCloser, err error) {
+	stdout, err = cmd.StdoutPipe()
+	if err != nil {
+		return
+	}
+	stderr, err = cmd.StderrPipe()
+	if err != nil {
+		return
+	}
+	Logf("Running %s", strings.Join(cmd.Args, " "))
+	err = cmd.Start()
+	return
+}
+
+// RunKubectl runs kubectl with args through DefaultKubectlRunner and returns
+// its combined stdout, or an error including stderr if it failed. Providers
+// that called SetDefaultKubectlRunner (e.g. to exec through an in-cluster
+// pod instead of the local apiserver connection) are honored transparently.
+func RunKubectl(args ...string) (string, error) {
+	return RunKubectlViaRunner(DefaultKubectlRunner(), nil, args...)
+}
+
+// RunKubectlOrDie runs kubectl with args via RunKubectl and fails the test
+// immediately if it returns an error.
+func RunKubectlOrDie(args ...string) string {
+	out, err := RunKubectl(args...)
+	if err != nil {
+		Failf("error running %v: %v", args, err)
+	}
+	return out
+}
+
+// RunKubectlOrDieInput is RunKubectlOrDie for commands that read from
+// stdin, e.g. "kubectl create -f -".
+func RunKubectlOrDieInput(data string, args ...string) string {
+	out, err := NewKubectlCommand(args...).WithStdinData(data).Exec()
+	if err != nil {
+		Failf("error running %v: %v", args, err)
+	}
+	return out
+}
+
+// KubectlBuilder assembles a single kubectl invocation -- optionally with
+// stdin data, a replacement environment, and a deadline -- and runs it
+// through DefaultKubectlRunner, the same indirection RunKubectl uses, so
+// providers that redirect kubectl execution apply here too.
+type KubectlBuilder struct {
+	args    []string
+	stdin   io.Reader
+	env     []string
+	timeout <-chan time.Time
+}
+
+// NewKubectlCommand returns a KubectlBuilder for "kubectl <args>".
+func NewKubectlCommand(args ...string) *KubectlBuilder {
+	return &KubectlBuilder{args: args}
+}
+
+// WithStdinData feeds data to the command's stdin.
+func (b *KubectlBuilder) WithStdinData(data string) *KubectlBuilder {
+	b.stdin = strings.NewReader(data)
+	return b
+}
+
+// WithStdinReader feeds r to the command's stdin as it is produced, for
+// interactive or streaming input.
+func (b *KubectlBuilder) WithStdinReader(r io.Reader) *KubectlBuilder {
+	b.stdin = r
+	return b
+}
+
+// WithEnv replaces the environment the command runs with. Runners that exec
+// kubectl somewhere other than this process (e.g. InClusterKubectlRunner)
+// forward env to wherever the actual kubectl process ends up running.
+func (b *KubectlBuilder) WithEnv(env []string) *KubectlBuilder {
+	b.env = env
+	return b
+}
+
+// WithTimeout fails the command if it has not completed by the time
+// timeout fires.
+func (b *KubectlBuilder) WithTimeout(timeout <-chan time.Time) *KubectlBuilder {
+	b.timeout = timeout
+	return b
+}
+
+// Exec runs the assembled command through DefaultKubectlRunner and returns
+// its combined stdout.
+func (b *KubectlBuilder) Exec() (string, error) {
+	runner := DefaultKubectlRunner()
+	stdin, stdout, stderr, wait, err := runner.Start(b.env, b.args...)
+	if err != nil {
+		return "", err
+	}
+
+	if b.stdin != nil {
+		go func() {
+			io.Copy(stdin, b.stdin)
+			stdin.Close()
+		}()
+	} else {
+		stdin.Close()
+	}
+
+	outCh := make(chan []byte, 1)
+	errCh := make(chan []byte, 1)
+	go func() {
+		buf := &bytes.Buffer{}
+		io.Copy(buf, stdout)
+		outCh <- buf.Bytes()
+	}()
+	go func() {
+		buf := &bytes.Buffer{}
+		io.Copy(buf, stderr)
+		errCh <- buf.Bytes()
+	}()
+
+	done := make(chan error, 1)
+	go func() { done <- wait() }()
+
+	if b.timeout == nil {
+		waitErr := <-done
+		out, errOut := <-outCh, <-errCh
+		if waitErr != nil {
+			return string(out), fmt.Errorf("error running %v: %v (stderr: %s)", b.args, waitErr, errOut)
+		}
+		return string(out), nil
+	}
+
+	select {
+	case waitErr := <-done:
+		out, errOut := <-outCh, <-errCh
+		if waitErr != nil {
+			return string(out), fmt.Errorf("error running %v: %v (stderr: %s)", b.args, waitErr, errOut)
+		}
+		return string(out), nil
+	case <-b.timeout:
+		return "", fmt.Errorf("timed out running %v", b.args)
+	}
+}
+
+// ExecOrDie runs the assembled command and fails the test immediately if it
+// returns an error.
+func (b *KubectlBuilder) ExecOrDie() string {
+	out, err := b.Exec()
+	if err != nil {
+		Failf("error running %v: %v", b.args, err)
+	}
+	return out
+}
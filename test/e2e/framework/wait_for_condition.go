@@ -0,0 +1,212 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"fmt"
+	"time"
+
+	"k8s.io/kubernetes/pkg/api"
+	client "k8s.io/kubernetes/pkg/client/unversioned"
+	"k8s.io/kubernetes/pkg/apis/extensions"
+	"k8s.io/kubernetes/pkg/util/jsonpath"
+	"k8s.io/kubernetes/pkg/util/wait"
+)
+
+// ResourceRef identifies a single object that WaitForCondition should poll.
+// Kind follows the same spelling used in kubectl's REST mapper (e.g. "Pod",
+// "Deployment", "ReplicationController", "Service", "Job", "PersistentVolumeClaim",
+// "PersistentVolume").
+type ResourceRef struct {
+	Kind      string
+	Namespace string
+	Name      string
+}
+
+func (r ResourceRef) String() string {
+	return fmt.Sprintf("%s/%s/%s", r.Kind, r.Namespace, r.Name)
+}
+
+// ConditionFunc evaluates whether obj currently satisfies a condition. A
+// non-nil error aborts the wait immediately (it is treated as terminal, not
+// retryable) so predicates should return (false, nil) for "not ready yet".
+type ConditionFunc func(obj interface{}) (bool, error)
+
+// WaitForCondition polls ref with client c every Poll interval until cond
+// reports the object satisfies the condition or timeout elapses. It is the
+// single polling engine behind the framework's resource-readiness helpers
+// (Ready, Deleted, Replicas, JSONPathEquals, HookPhase) so that ad-hoc
+// wait.PollImmediate loops scattered across e2e tests can share one
+// implementation, one error format, and one source of debug output.
+func WaitForCondition(c *client.Client, ref ResourceRef, cond ConditionFunc, timeout time.Duration) error {
+	var lastErr error
+	err := wait.PollImmediate(Poll, timeout, func() (bool, error) {
+		obj, err := getResource(c, ref)
+		if err != nil {
+			lastErr = err
+			// A not-found object is meaningful to the Deleted predicate, so
+			// let the condition function see it rather than aborting here.
+			if apiErrorIsNotFound(err) {
+				return cond(nil)
+			}
+			return false, nil
+		}
+		ok, err := cond(obj)
+		if err != nil {
+			return false, err
+		}
+		return ok, nil
+	})
+	if err != nil {
+		return fmt.Errorf("waiting for condition on %s: %v (last get error: %v)", ref, err, lastErr)
+	}
+	return nil
+}
+
+func apiErrorIsNotFound(err error) bool {
+	return client.IsNotFound(err)
+}
+
+func getResource(c *client.Client, ref ResourceRef) (interface{}, error) {
+	switch ref.Kind {
+	case "Pod":
+		return c.Pods(ref.Namespace).Get(ref.Name)
+	case "Deployment":
+		return c.Extensions().Deployments(ref.Namespace).Get(ref.Name)
+	case "ReplicationController":
+		return c.ReplicationControllers(ref.Namespace).Get(ref.Name)
+	case "Service":
+		return c.Services(ref.Namespace).Get(ref.Name)
+	case "Job":
+		return c.Extensions().Jobs(ref.Namespace).Get(ref.Name)
+	case "PersistentVolumeClaim":
+		return c.PersistentVolumeClaims(ref.Namespace).Get(ref.Name)
+	case "PersistentVolume":
+		return c.PersistentVolumes().Get(ref.Name)
+	default:
+		return nil, fmt.Errorf("WaitForCondition: unsupported resource kind %q", ref.Kind)
+	}
+}
+
+// Ready is satisfied once the object's own readiness condition is true: a
+// Pod's PodReady condition, a Deployment's available replica count, or a
+// ReplicationController/Job reaching its desired replica/completion count.
+func Ready() ConditionFunc {
+	return func(obj interface{}) (bool, error) {
+		if obj == nil {
+			return false, nil
+		}
+		switch o := obj.(type) {
+		case *api.Pod:
+			for _, c := range o.Status.Conditions {
+				if c.Type == api.PodReady {
+					return c.Status == api.ConditionTrue, nil
+				}
+			}
+			return false, nil
+		case *extensions.Deployment:
+			return o.Status.AvailableReplicas >= o.Spec.Replicas, nil
+		case *api.ReplicationController:
+			return o.Status.Replicas == o.Spec.Replicas, nil
+		case *extensions.Job:
+			want := int32(1)
+			if o.Spec.Completions != nil {
+				want = *o.Spec.Completions
+			}
+			return o.Status.Succeeded >= want, nil
+		default:
+			return false, fmt.Errorf("Ready: unsupported object type %T", obj)
+		}
+	}
+}
+
+// Deleted is satisfied once Get for the resource returns NotFound.
+func Deleted() ConditionFunc {
+	return func(obj interface{}) (bool, error) {
+		return obj == nil, nil
+	}
+}
+
+// Replicas is satisfied once a ReplicationController reports n replicas in
+// .status.replicas, or a Deployment reports n available replicas.
+func Replicas(n int32) ConditionFunc {
+	return func(obj interface{}) (bool, error) {
+		switch o := obj.(type) {
+		case *api.ReplicationController:
+			return o.Status.Replicas == n, nil
+		case *extensions.Deployment:
+			return o.Status.AvailableReplicas == n, nil
+		default:
+			return false, fmt.Errorf("Replicas: unsupported object type %T", obj)
+		}
+	}
+}
+
+// JSONPathEquals evaluates expr (a kubectl-style jsonpath expression, e.g.
+// "{.spec.ports[0].nodePort}") against obj and reports whether the
+// stringified result equals value.
+func JSONPathEquals(expr, value string) ConditionFunc {
+	return func(obj interface{}) (bool, error) {
+		if obj == nil {
+			return false, nil
+		}
+		jp := jsonpath.New("WaitForCondition")
+		if err := jp.Parse(expr); err != nil {
+			return false, fmt.Errorf("JSONPathEquals: invalid expression %q: %v", expr, err)
+		}
+		result, err := jp.FindResults(obj)
+		if err != nil {
+			return false, nil
+		}
+		for _, arr := range result {
+			for _, r := range arr {
+				if fmt.Sprintf("%v", r.Interface()) == value {
+					return true, nil
+				}
+			}
+		}
+		return false, nil
+	}
+}
+
+// HookPhase is satisfied once a Job reaches the given completion phase
+// ("Complete" or "Failed", mirroring batch Job condition types).
+func HookPhase(phase string) ConditionFunc {
+	return func(obj interface{}) (bool, error) {
+		job, ok := obj.(*extensions.Job)
+		if !ok {
+			return false, fmt.Errorf("HookPhase: unsupported object type %T", obj)
+		}
+		for _, c := range job.Status.Conditions {
+			if string(c.Type) == phase {
+				return c.Status == api.ConditionTrue, nil
+			}
+		}
+		return false, nil
+	}
+}
+
+// KubectlWaitOrDie mimics "kubectl wait --for=condition=<cond> <kind>/<name>
+// --timeout=<d>" by driving the same polling engine as WaitForCondition
+// directly, so e2e tests can express assertions in the kubectl-wait idiom
+// (declaratively, via a ConditionFunc) without shelling out to the
+// "kubectl wait" subcommand.
+func KubectlWaitOrDie(c *client.Client, ref ResourceRef, cond ConditionFunc, timeout time.Duration) {
+	if err := WaitForCondition(c, ref, cond, timeout); err != nil {
+		Failf("kubectl wait %s: %v", ref, err)
+	}
+}
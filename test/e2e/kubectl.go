@@ -17,17 +17,19 @@ limitations under the License.
 package e2e
 
 import (
+	"bufio"
 	"bytes"
+	"crypto/sha256"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"log"
-	"mime/multipart"
 	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
 	"os/exec"
 	"path"
@@ -36,10 +38,15 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/armon/go-socks5"
 	"github.com/elazarl/goproxy"
+	"github.com/elazarl/goproxy/ext/auth"
 	"github.com/ghodss/yaml"
+	"golang.org/x/net/context"
 
 	"k8s.io/kubernetes/pkg/api"
 	"k8s.io/kubernetes/pkg/api/annotations"
@@ -47,9 +54,12 @@ import (
 	"k8s.io/kubernetes/pkg/api/unversioned"
 	client "k8s.io/kubernetes/pkg/client/unversioned"
 	"k8s.io/kubernetes/pkg/controller"
+	"k8s.io/kubernetes/pkg/dockercompat"
+	"k8s.io/kubernetes/pkg/kubectl/cmd"
 	"k8s.io/kubernetes/pkg/kubectl/cmd/util"
 	"k8s.io/kubernetes/pkg/labels"
 	"k8s.io/kubernetes/pkg/registry/generic/registry"
+	"k8s.io/kubernetes/pkg/runtime"
 	pkgutil "k8s.io/kubernetes/pkg/util"
 	utilnet "k8s.io/kubernetes/pkg/util/net"
 	"k8s.io/kubernetes/pkg/util/wait"
@@ -185,6 +195,10 @@ var _ = framework.KubeDescribe("Kubectl client", func() {
 	// idiomatic way to wrap the ClusterVerification structs for syntactic sugar in large
 	// test files.
 	// Print debug info if atLeast Pods are not found before the timeout
+	//
+	// Left on ClusterVerification rather than framework.WaitForCondition: it waits for
+	// "at least N pods matching a label selector", not a single named ResourceRef, which
+	// ConditionFunc has no way to express.
 	waitForOrFailWithDebug := func(atLeast int) {
 		pods, err := clusterState().WaitFor(atLeast, framework.PodStartTimeout)
 		if err != nil || len(pods) < atLeast {
@@ -264,6 +278,47 @@ var _ = framework.KubeDescribe("Kubectl client", func() {
 			By("validating guestbook app")
 			validateGuestbookApp(c, ns)
 		})
+
+		It("should expose running components through the Docker-compatible shim", func() {
+			framework.SkipUnlessServerVersionGTE(deploymentsVersion, c)
+
+			defer forEachGBFile(func(contents string) {
+				cleanupKubectlInputs(contents, ns)
+			})
+			By("creating all guestbook components")
+			forEachGBFile(func(contents string) {
+				framework.RunKubectlOrDieInput(contents, "create", "-f", "-", fmt.Sprintf("--namespace=%v", ns))
+			})
+
+			By("waiting for guestbook to serve content")
+			validateGuestbookApp(c, ns)
+
+			By("listing containers through the dockercompat shim")
+			// dockercompat.NewHandler is meant to be mounted behind the
+			// apiserver's /proxy subresource, not hit directly: real Docker
+			// tooling never sees the shim's own address, only a Service it's
+			// proxied through. This snapshot has no image that bakes the
+			// shim into a container, so the handler still has to run in this
+			// process -- but a headless Service plus hand-built Endpoints
+			// pointing at it lets the test genuinely go through the
+			// apiserver's services/proxy subresource instead of bypassing it
+			// with a bare http.Get against the shim's own httptest URL.
+			shim := httptest.NewServer(dockercompat.NewHandler(c))
+			defer shim.Close()
+
+			shimSvc := mountDockerCompatShim(c, ns, shim)
+			defer func() {
+				c.Services(ns).Delete(shimSvc)
+				c.Endpoints(ns).Delete(shimSvc)
+			}()
+
+			var names []string
+			Eventually(func() []string {
+				names = dockerCompatContainerNames(c, ns, shimSvc)
+				return names
+			}, framework.PodStartTimeout, framework.Poll).Should(ContainElement(ContainSubstring("frontend")))
+			Expect(names).To(ContainElement(ContainSubstring("redis-master")))
+		})
 	})
 
 	framework.KubeDescribe("Simple pod", func() {
@@ -273,7 +328,7 @@ var _ = framework.KubeDescribe("Kubectl client", func() {
 			podPath = framework.ReadOrDie(path.Join(kubeCtlManifestPath, "pod-with-readiness-probe.yaml"))
 			By(fmt.Sprintf("creating the pod from %v", string(podPath)))
 			framework.RunKubectlOrDieInput(string(podPath[:]), "create", "-f", "-", fmt.Sprintf("--namespace=%v", ns))
-			Expect(framework.CheckPodsRunningReady(c, ns, []string{simplePodName}, framework.PodStartTimeout)).To(BeTrue())
+			framework.KubectlWaitOrDie(c, framework.ResourceRef{Kind: "Pod", Namespace: ns, Name: simplePodName}, framework.Ready(), framework.PodStartTimeout)
 		})
 		AfterEach(func() {
 			cleanupKubectlInputs(string(podPath[:]), ns, simplePodSelector)
@@ -312,9 +367,14 @@ var _ = framework.KubeDescribe("Kubectl client", func() {
 		})
 
 		It("should support exec through an HTTP proxy", func() {
-			// Note: We are skipping local since we want to verify an apiserver with HTTPS.
-			// At this time local only supports plain HTTP.
-			framework.SkipIfProviderIs("local")
+			// Note: on the "local" provider the apiserver only speaks plain
+			// HTTP, so exec-ing through this host's kubectl can't exercise
+			// an HTTPS proxy dial. Route through a kubectl that runs inside
+			// the cluster instead of skipping the test outright.
+			if framework.ProviderIs("local") {
+				framework.SetDefaultKubectlRunner(framework.NewInClusterKubectlRunner(c, ns, simplePodName, "nginx"))
+				defer framework.SetDefaultKubectlRunner(nil)
+			}
 			// Fail if the variable isn't set
 			if framework.TestContext.Host == "" {
 				framework.Failf("--host variable must be set to the full URI to the api server on e2e run.")
@@ -339,7 +399,7 @@ var _ = framework.KubeDescribe("Kubectl client", func() {
 				}
 
 				// Verify the proxy server logs saw the connection
-				expectedProxyLog := fmt.Sprintf("Accepting CONNECT to %s", strings.TrimRight(strings.TrimLeft(framework.TestContext.Host, "https://"), "/api"))
+				expectedProxyLog := fmt.Sprintf("Accepting CONNECT to %s", apiServerHostname())
 
 				proxyLog := proxyLogs.String()
 				if !strings.Contains(proxyLog, expectedProxyLog) {
@@ -348,6 +408,86 @@ var _ = framework.KubeDescribe("Kubectl client", func() {
 			}
 		})
 
+		It("should support kubectl proxy's apiserver connection through an authenticated HTTP proxy", func() {
+			framework.SkipIfProviderIs("local")
+			if framework.TestContext.Host == "" {
+				framework.Failf("--host variable must be set to the full URI to the api server on e2e run.")
+			}
+
+			By("Starting goproxy with Basic auth required")
+			const proxyUser, proxyPass = "e2e-user", "e2e-pass"
+			testSrv, proxyLogs := startLocalProxyWithAuth(proxyUser, proxyPass)
+			defer testSrv.Close()
+
+			By("Running kubectl proxy via an authenticated HTTP proxy")
+			proxyURL := fmt.Sprintf("http://%s:%s@%s", proxyUser, proxyPass, strings.TrimPrefix(testSrv.URL, "http://"))
+			addr, stop, err := startKubectlProxy(append(os.Environ(), fmt.Sprintf("HTTPS_PROXY=%s", proxyURL)))
+			if err != nil {
+				framework.Failf("Starting kubectl proxy: %v", err)
+			}
+			defer stop()
+
+			if _, err := curl(fmt.Sprintf("http://%s/api", addr)); err != nil {
+				framework.Failf("Querying kubectl proxy: %v", err)
+			}
+			if !strings.Contains(proxyLogs.String(), "Accepting CONNECT") {
+				framework.Failf("Missing expected CONNECT log on authenticated proxy, got %q", proxyLogs.String())
+			}
+		})
+
+		It("should support kubectl proxy's apiserver connection through a SOCKS5 proxy", func() {
+			framework.SkipIfProviderIs("local")
+			if framework.TestContext.Host == "" {
+				framework.Failf("--host variable must be set to the full URI to the api server on e2e run.")
+			}
+
+			By("Starting a SOCKS5 proxy")
+			socksAddr, connCount, stop := startLocalSocks5Proxy()
+			defer stop()
+
+			By("Running kubectl proxy via ALL_PROXY pointed at the SOCKS5 proxy")
+			addr, stopProxy, err := startKubectlProxy(append(os.Environ(), fmt.Sprintf("ALL_PROXY=socks5://%s", socksAddr)))
+			if err != nil {
+				framework.Failf("Starting kubectl proxy: %v", err)
+			}
+			defer stopProxy()
+
+			if _, err := curl(fmt.Sprintf("http://%s/api", addr)); err != nil {
+				framework.Failf("Querying kubectl proxy: %v", err)
+			}
+			if connCount() < 1 {
+				framework.Failf("Expected at least one connection to traverse the SOCKS5 proxy, got %d", connCount())
+			}
+		})
+
+		It("should bypass the proxy for hosts matching NO_PROXY", func() {
+			framework.SkipIfProviderIs("local")
+			if framework.TestContext.Host == "" {
+				framework.Failf("--host variable must be set to the full URI to the api server on e2e run.")
+			}
+
+			By("Starting goproxy")
+			testSrv, proxyLogs := startLocalProxy()
+			defer testSrv.Close()
+
+			apiHost := apiServerHostname()
+			By("Running kubectl proxy with the apiserver host listed in NO_PROXY")
+			addr, stop, err := startKubectlProxy(append(os.Environ(),
+				fmt.Sprintf("HTTPS_PROXY=%s", testSrv.URL),
+				fmt.Sprintf("NO_PROXY=%s", apiHost)))
+			if err != nil {
+				framework.Failf("Starting kubectl proxy: %v", err)
+			}
+			defer stop()
+
+			if _, err := curl(fmt.Sprintf("http://%s/api", addr)); err != nil {
+				framework.Failf("Querying kubectl proxy: %v", err)
+			}
+			if strings.Contains(proxyLogs.String(), "Accepting CONNECT") {
+				framework.Failf("Expected NO_PROXY to bypass the proxy entirely, but saw a CONNECT: %q", proxyLogs.String())
+			}
+		})
+
 		It("should support inline execution and attach", func() {
 			framework.SkipUnlessServerVersionGTE(jobsVersion, c)
 
@@ -379,14 +519,13 @@ var _ = framework.KubeDescribe("Kubectl client", func() {
 			if err != nil {
 				os.Exit(1)
 			}
-			if !framework.CheckPodsRunningReady(c, ns, []string{runTestPod.Name}, time.Minute) {
-				framework.Failf("Pod %q of Job %q should still be running", runTestPod.Name, "run-test-3")
-			}
+			framework.KubectlWaitOrDie(c, framework.ResourceRef{Kind: "Pod", Namespace: ns, Name: runTestPod.Name}, framework.Ready(), time.Minute)
 
 			// NOTE: we cannot guarantee our output showed up in the container logs before stdin was closed, so we have
-			// to loop test.
+			// to loop test. The pod readiness check stays a plain WaitForCondition guard each iteration (not the loop's
+			// exit condition), since what we're actually waiting on here is log content, not resource state.
 			err = wait.PollImmediate(time.Second, time.Minute, func() (bool, error) {
-				if !framework.CheckPodsRunningReady(c, ns, []string{runTestPod.Name}, 1*time.Second) {
+				if err := framework.WaitForCondition(c, framework.ResourceRef{Kind: "Pod", Namespace: ns, Name: runTestPod.Name}, framework.Ready(), 1*time.Second); err != nil {
 					framework.Failf("Pod %q of Job %q should still be running", runTestPod.Name, "run-test-3")
 				}
 				logOutput := framework.RunKubectlOrDie(nsFlag, "logs", runTestPod.Name)
@@ -419,6 +558,76 @@ var _ = framework.KubeDescribe("Kubectl client", func() {
 		})
 	})
 
+	framework.KubeDescribe("Kubectl event bus", func() {
+		It("should serve published validation events over its HTTP endpoint", func() {
+			server := httptest.NewServer(framework.DefaultEventBus)
+			defer server.Close()
+
+			const message = "smoke-tested via the event bus HTTP endpoint"
+			publishValidationEvent("event-bus-smoke-test", ns, message)
+
+			By("querying the event bus over HTTP")
+			body, err := curl(fmt.Sprintf("%s/events?filter=kind=validation,namespace=%s", server.URL, ns))
+			if err != nil {
+				framework.Failf("Querying event bus over HTTP: %v", err)
+			}
+			if !strings.Contains(body, message) {
+				framework.Failf("Expected the published event in the HTTP response, got %q", body)
+			}
+		})
+	})
+
+	framework.KubeDescribe("Kubectl file upload", func() {
+		It("should stream a file to an HTTP endpoint via a resumable chunked upload", func() {
+			received := sha256.New()
+			var receivedSize int64
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mr, err := r.MultipartReader()
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+				part, err := mr.NextPart()
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+				n, err := io.Copy(received, part)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+				receivedSize += n
+			}))
+			defer server.Close()
+
+			By("writing a local file to upload")
+			tmpFile, err := ioutil.TempFile("", "kubectl-upload-test")
+			if err != nil {
+				framework.Failf("Error creating temp file: %v", err)
+			}
+			defer os.Remove(tmpFile.Name())
+			contents := bytes.Repeat([]byte("0123456789abcdef"), 1024)
+			if _, err := tmpFile.Write(contents); err != nil {
+				framework.Failf("Error writing temp file: %v", err)
+			}
+			tmpFile.Close()
+
+			By("uploading the file to the HTTP endpoint")
+			if err := uploadFileResumable(server.URL, tmpFile.Name()); err != nil {
+				framework.Failf("uploadFileResumable failed: %v", err)
+			}
+
+			wantDigest := sha256.Sum256(contents)
+			if got := fmt.Sprintf("%x", received.Sum(nil)); got != fmt.Sprintf("%x", wantDigest) {
+				framework.Failf("Server received content with digest %s, want %x", got, wantDigest)
+			}
+			if receivedSize != int64(len(contents)) {
+				framework.Failf("Server received %d bytes, want %d", receivedSize, len(contents))
+			}
+		})
+	})
+
 	framework.KubeDescribe("Kubectl api-versions", func() {
 		It("should check if v1 is in available api versions [Conformance]", func() {
 			By("validating api verions")
@@ -444,6 +653,40 @@ var _ = framework.KubeDescribe("Kubectl client", func() {
 			By("checking the result")
 			forEachReplicationController(c, ns, "app", "redis", validateReplicationControllerConfiguration)
 		})
+		It("should retry a conflicting RC update via GuardedUpdate", func() {
+			controllerJson := readTestFileOrDie(redisControllerFilename)
+			nsFlag := fmt.Sprintf("--namespace=%v", ns)
+
+			By("creating Redis RC")
+			framework.RunKubectlOrDieInput(string(controllerJson), "create", "-f", "-", nsFlag)
+
+			By("reading the current RC to seed a known-current GuardedUpdate")
+			rc, err := c.ReplicationControllers(ns).Get("redis-master")
+			Expect(err).NotTo(HaveOccurred())
+
+			By("racing an out-of-band update in to force a conflict")
+			racing, err := c.ReplicationControllers(ns).Get("redis-master")
+			Expect(err).NotTo(HaveOccurred())
+			racing.Labels[applyTestLabel] = "RACED"
+			_, err = c.ReplicationControllers(ns).Update(racing)
+			Expect(err).NotTo(HaveOccurred())
+
+			By("applying a GuardedUpdate against the now-stale copy")
+			rcClient := guardedRCClient{c: c, ns: ns, name: "redis-master"}
+			ctx, cancel := context.WithTimeout(context.Background(), framework.PodListTimeout)
+			defer cancel()
+			result, err := framework.GuardedUpdate(ctx, rcClient, runtime.Object(rc), true, func(obj runtime.Object) (runtime.Object, error) {
+				current := obj.(*api.ReplicationController)
+				current.Labels[applyTestLabel] = "GUARDED"
+				return current, nil
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			By("checking that the guarded mutation was applied on top of the refreshed RC")
+			updated := result.(*api.ReplicationController)
+			Expect(updated.Labels[applyTestLabel]).To(Equal("GUARDED"))
+			Expect(updated.Labels["app"]).To(Equal("redis"))
+		})
 		It("should reuse nodePort when apply to an existing SVC", func() {
 			serviceJson := readTestFileOrDie(redisServiceFilename)
 			nsFlag := fmt.Sprintf("--namespace=%v", ns)
@@ -457,13 +700,103 @@ var _ = framework.KubeDescribe("Kubectl client", func() {
 			By("applying the same configuration")
 			framework.RunKubectlOrDieInput(string(serviceJson[:]), "apply", "-f", "-", nsFlag)
 
-			By("getting the nodePort after applying configuration")
-			currentNodePort := framework.RunKubectlOrDie("get", "service", "redis-master", nsFlag, "-o", "jsonpath={.spec.ports[0].nodePort}")
-
 			By("checking the result")
-			if originalNodePort != currentNodePort {
-				framework.Failf("nodePort should keep the same")
-			}
+			ref := framework.ResourceRef{Kind: "Service", Namespace: ns, Name: "redis-master"}
+			framework.KubectlWaitOrDie(c, ref, framework.JSONPathEquals("{.spec.ports[0].nodePort}", originalNodePort), framework.PodStartTimeout)
+		})
+		It("should retain objects marked with the keep-on-delete annotation", func() {
+			controllerJson := readTestFileOrDie(redisControllerFilename)
+			serviceJson := readTestFileOrDie(redisServiceFilename)
+			nsFlag := fmt.Sprintf("--namespace=%v", ns)
+			configMapName := "redis-config"
+
+			By("applying an RC and a Service as one set, then creating a ConfigMap marked to keep-on-delete")
+			trio := string(controllerJson) + "\n---\n" + string(serviceJson)
+			framework.RunKubectlOrDieInput(trio, "apply", "-f", "-", "-l", "app=redis", nsFlag)
+			_, err := c.ConfigMaps(ns).Create(&api.ConfigMap{
+				ObjectMeta: api.ObjectMeta{
+					Name:   configMapName,
+					Labels: map[string]string{"app": "redis"},
+					Annotations: map[string]string{
+						cmd.ResourcePolicyAnnotation: "keep",
+					},
+				},
+				Data: map[string]string{"key": "value"},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			By("re-applying a manifest that omits the whole trio, pruning the labeled set")
+			framework.RunKubectlOrDieInput("", "apply", "-f", "-", "--prune", "-l", "app=redis", nsFlag)
+
+			By("checking that the RC and Service were pruned")
+			_, err = c.ReplicationControllers(ns).Get("redis-master")
+			Expect(err).To(HaveOccurred())
+			Expect(apierrs.IsNotFound(err)).To(BeTrue())
+			_, err = c.Services(ns).Get("redis-master")
+			Expect(err).To(HaveOccurred())
+			Expect(apierrs.IsNotFound(err)).To(BeTrue())
+
+			By("checking that the annotated ConfigMap was retained and detached from apply")
+			cm, err := c.ConfigMaps(ns).Get(configMapName)
+			Expect(err).NotTo(HaveOccurred())
+			_, hasLastApplied := cm.Annotations[annotations.LastAppliedConfigAnnotation]
+			Expect(hasLastApplied).To(BeFalse())
+		})
+	})
+
+	framework.KubeDescribe("Kubectl apply with values", func() {
+		const podTemplate = `apiVersion: v1
+kind: Pod
+metadata:
+  name: {{.name}}
+spec:
+  containers:
+  - name: app
+    image: {{.image.repository}}:{{.image.tag}}
+  restartPolicy: Never
+`
+		podName := "e2e-test-apply-values-pod"
+		nsFlag := func() string { return fmt.Sprintf("--namespace=%v", ns) }
+
+		AfterEach(func() {
+			framework.RunKubectlOrDie("delete", "pod", podName, nsFlag(), "--ignore-not-found")
+		})
+
+		It("should override the image/tag via --set", func() {
+			By("applying with --set overriding the tag")
+			framework.RunKubectlOrDieInput(podTemplate, "apply", "-f", "-",
+				"--set", "name="+podName,
+				"--set", "image.repository=gcr.io/google_containers/busybox",
+				"--set", "image.tag=latest",
+				nsFlag())
+
+			pod, err := c.Pods(ns).Get(podName)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pod.Spec.Containers[0].Image).To(Equal("gcr.io/google_containers/busybox:latest"))
+		})
+
+		It("should let --set take precedence over --values", func() {
+			valuesFile, err := ioutil.TempFile("", "kubectl-apply-values")
+			Expect(err).NotTo(HaveOccurred())
+			defer os.Remove(valuesFile.Name())
+			_, err = valuesFile.WriteString("image:\n  repository: gcr.io/google_containers/busybox\n  tag: \"1.24\"\n")
+			Expect(err).NotTo(HaveOccurred())
+			valuesFile.Close()
+
+			framework.RunKubectlOrDieInput(podTemplate, "apply", "-f", "-",
+				"--values", valuesFile.Name(),
+				"--set", "name="+podName,
+				"--set", "image.tag=latest",
+				nsFlag())
+
+			pod, err := c.Pods(ns).Get(podName)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pod.Spec.Containers[0].Image).To(Equal("gcr.io/google_containers/busybox:latest"))
+		})
+
+		It("should reject a malformed --set key", func() {
+			_, err := cmd.ParseSetValues([]string{"no-equals-sign-here"})
+			Expect(err).To(HaveOccurred())
 		})
 	})
 
@@ -667,7 +1000,7 @@ var _ = framework.KubeDescribe("Kubectl client", func() {
 			By("creating the pod")
 			nsFlag = fmt.Sprintf("--namespace=%v", ns)
 			framework.RunKubectlOrDieInput(string(pod), "create", "-f", "-", nsFlag)
-			Expect(framework.CheckPodsRunningReady(c, ns, []string{pausePodName}, framework.PodStartTimeout)).To(BeTrue())
+			framework.KubectlWaitOrDie(c, framework.ResourceRef{Kind: "Pod", Namespace: ns, Name: pausePodName}, framework.Ready(), framework.PodStartTimeout)
 		})
 		AfterEach(func() {
 			cleanupKubectlInputs(string(pod[:]), ns, pausePodSelector)
@@ -759,6 +1092,107 @@ var _ = framework.KubeDescribe("Kubectl client", func() {
 				Expect(recent).To(BeNumerically("<", older), "expected recent(%v) to be less than older(%v)\nrecent lines:\n%v\nolder lines:\n%v\n", recent, older, recent_out, older_out)
 			})
 		})
+
+		// These cases exercise --follow, --previous, and per-container
+		// selection against a pod with a sidecar and an init container of
+		// its own, rather than the shared redis-master-controller fixture,
+		// since --all-containers/-c selection needs more than one
+		// long-running container to be meaningful.
+		framework.KubeDescribe("with a multi-container pod", func() {
+			multiPodName := "execpod-multi-container"
+			mainContainer := "main"
+			sidecarContainer := "sidecar"
+			initContainer := "init"
+
+			BeforeEach(func() {
+				_, err := c.Pods(ns).Create(&api.Pod{
+					ObjectMeta: api.ObjectMeta{Name: multiPodName},
+					Spec: api.PodSpec{
+						InitContainers: []api.Container{
+							{
+								Name:    initContainer,
+								Image:   busyboxImage,
+								Command: []string{"sh", "-c", "echo init-done"},
+							},
+						},
+						Containers: []api.Container{
+							{
+								Name:    mainContainer,
+								Image:   busyboxImage,
+								Command: []string{"sh", "-c", "i=0; while true; do echo main-line-$i; i=$((i+1)); sleep 1; done"},
+							},
+							{
+								Name:    sidecarContainer,
+								Image:   busyboxImage,
+								Command: []string{"sh", "-c", "i=0; while true; do echo sidecar-line-$i; i=$((i+1)); sleep 1; done"},
+							},
+						},
+						RestartPolicy: api.RestartPolicyAlways,
+					},
+				})
+				Expect(err).NotTo(HaveOccurred())
+				framework.KubectlWaitOrDie(c, framework.ResourceRef{Kind: "Pod", Namespace: ns, Name: multiPodName}, framework.Ready(), framework.PodStartTimeout)
+			})
+			AfterEach(func() {
+				framework.RunKubectlOrDie("delete", "pod", multiPodName, nsFlag)
+			})
+
+			It("should stream new lines with --follow", func() {
+				By("starting kubectl logs --follow")
+				cmd := framework.KubectlCmd("logs", "-f", multiPodName, "-c", mainContainer, nsFlag)
+				stdout, stderr, err := framework.StartCmdAndStreamOutput(cmd)
+				Expect(err).NotTo(HaveOccurred())
+				defer stdout.Close()
+				defer stderr.Close()
+				defer framework.TryKill(cmd)
+
+				By("verifying new lines appear in the stream after it starts")
+				buf := make([]byte, 4096)
+				Eventually(func() string {
+					n, _ := stdout.Read(buf)
+					return string(buf[:n])
+				}, 30*time.Second, time.Second).Should(ContainSubstring("main-line-"))
+			})
+
+			It("should return logs from a terminated container with --previous", func() {
+				By("killing the main container to force a restart")
+				_, err := framework.RunKubectl("exec", multiPodName, "-c", mainContainer, nsFlag, "--", "sh", "-c", "kill 1")
+				// The exec connection is expected to be torn down along with the container.
+				_ = err
+
+				By("waiting for the container to restart")
+				Eventually(func() (int32, error) {
+					pod, err := c.Pods(ns).Get(multiPodName)
+					if err != nil {
+						return 0, err
+					}
+					for _, cs := range pod.Status.ContainerStatuses {
+						if cs.Name == mainContainer {
+							return cs.RestartCount, nil
+						}
+					}
+					return 0, fmt.Errorf("container %s not found", mainContainer)
+				}, framework.PodStartTimeout, 2*time.Second).Should(BeNumerically(">", 0))
+
+				By("verifying --previous returns logs from the terminated instance")
+				out := framework.RunKubectlOrDie("logs", "--previous", multiPodName, "-c", mainContainer, nsFlag)
+				Expect(out).To(ContainSubstring("main-line-"))
+			})
+
+			It("should select a single container with -c and all containers with --all-containers --prefix", func() {
+				By("selecting just the sidecar container")
+				out := framework.RunKubectlOrDie("logs", multiPodName, "-c", sidecarContainer, nsFlag)
+				Expect(out).To(ContainSubstring("sidecar-line-"))
+				Expect(out).NotTo(ContainSubstring("main-line-"))
+
+				By("selecting all containers with --all-containers --prefix")
+				out = framework.RunKubectlOrDie("logs", multiPodName, "--all-containers=true", "--prefix=true", nsFlag)
+				Expect(out).To(ContainSubstring(fmt.Sprintf("[pod/%s/%s]", multiPodName, mainContainer)))
+				Expect(out).To(ContainSubstring(fmt.Sprintf("[pod/%s/%s]", multiPodName, sidecarContainer)))
+				Expect(out).To(ContainSubstring("main-line-"))
+				Expect(out).To(ContainSubstring("sidecar-line-"))
+			})
+		})
 	})
 
 	framework.KubeDescribe("Kubectl patch", func() {
@@ -886,8 +1320,8 @@ var _ = framework.KubeDescribe("Kubectl client", func() {
 			for _, pod := range pods {
 				podNames = append(podNames, pod.Name)
 			}
-			if !framework.CheckPodsRunningReady(c, ns, podNames, framework.PodStartTimeout) {
-				framework.Failf("Pods for rc %s were not ready", rcName)
+			for _, podName := range podNames {
+				framework.KubectlWaitOrDie(c, framework.ResourceRef{Kind: "Pod", Namespace: ns, Name: podName}, framework.Ready(), framework.PodStartTimeout)
 			}
 			_, err = framework.RunKubectl("logs", "rc/"+rcName, nsFlag)
 			// a non-nil error is fine as long as we actually found a pod.
@@ -933,6 +1367,53 @@ var _ = framework.KubeDescribe("Kubectl client", func() {
 		})
 	})
 
+	framework.KubeDescribe("Kubectl wait", func() {
+		var nsFlag string
+		BeforeEach(func() {
+			nsFlag = fmt.Sprintf("--namespace=%v", ns)
+		})
+
+		It("should block until a pod's Ready condition is met", func() {
+			podName := "e2e-test-wait-ready-pod"
+			framework.RunKubectlOrDie("run", podName, "--image="+nginxImage, "--restart=Never", "--generator=run-pod/v1", nsFlag)
+			defer framework.RunKubectlOrDie("delete", "pod", podName, nsFlag)
+
+			framework.RunKubectlOrDie("wait", "--for=condition=Ready", "pod/"+podName, "--timeout=2m", nsFlag)
+		})
+
+		It("should exit non-zero on timeout when the condition is never met", func() {
+			podName := "e2e-test-wait-timeout-pod"
+			// An image that will never be pulled keeps the pod out of Ready.
+			framework.RunKubectlOrDie("run", podName, "--image=does-not-exist/unreachable:latest", "--restart=Never", "--generator=run-pod/v1", nsFlag)
+			defer framework.RunKubectlOrDie("delete", "pod", podName, nsFlag)
+
+			_, err := framework.RunKubectl("wait", "--for=condition=Ready", "pod/"+podName, "--timeout=5s", nsFlag)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should block until a deleted deployment is gone", func() {
+			framework.SkipUnlessServerVersionGTE(deploymentsVersion, c)
+			dName := "e2e-test-wait-delete-deployment"
+			framework.RunKubectlOrDie("run", dName, "--image="+nginxImage, "--generator=deployment/v1beta1", nsFlag)
+
+			framework.RunKubectlOrDie("delete", "deployment", dName, nsFlag)
+			framework.RunKubectlOrDie("wait", "--for=delete", "deployment/"+dName, "--timeout=2m", nsFlag)
+
+			_, err := c.Extensions().Deployments(ns).Get(dName)
+			Expect(err).To(HaveOccurred())
+			Expect(apierrs.IsNotFound(err)).To(BeTrue())
+		})
+
+		It("should block until a job's Complete condition is met", func() {
+			framework.SkipUnlessServerVersionGTE(jobsVersion, c)
+			jobName := "e2e-test-wait-complete-job"
+			framework.RunKubectlOrDie("run", jobName, "--image="+busyboxImage, "--restart=OnFailure", "--generator=job/v1", nsFlag)
+			defer framework.RunKubectlOrDie("delete", "jobs", jobName, nsFlag)
+
+			framework.RunKubectlOrDie("wait", "--for=condition=Complete", "job/"+jobName, "--timeout=2m", nsFlag)
+		})
+	})
+
 	framework.KubeDescribe("Kubectl run deployment", func() {
 		var nsFlag string
 		var dName string
@@ -1065,6 +1546,56 @@ var _ = framework.KubeDescribe("Kubectl client", func() {
 		})
 	})
 
+	framework.KubeDescribe("Kubectl private registry pull", func() {
+		It("should pull an image from a private registry using imagePullSecrets", func() {
+			if !framework.PrivateRegistryConfigured() {
+				Skip("--docker-registry and --docker-registry-secret must both be set to run this test")
+			}
+
+			nsFlag := fmt.Sprintf("--namespace=%v", ns)
+			podName := "private-registry-pull-test"
+			secretName := "private-registry-secret"
+			marker := "e2e-private-registry-marker"
+
+			By("building and pushing a test image to the private registry")
+			image, err := framework.BuildAndPushPrivateRegistryTestImage("e2e")
+			Expect(err).NotTo(HaveOccurred())
+
+			By("creating a dockerconfigjson imagePullSecret from --docker-registry-secret")
+			secretData := readBytesFromFile(framework.PrivateRegistryTestContext.RegistrySecretFile)
+			_, err = c.Secrets(ns).Create(&api.Secret{
+				ObjectMeta: api.ObjectMeta{Name: secretName},
+				Type:       api.SecretTypeDockerConfigJson,
+				Data:       map[string][]byte{api.DockerConfigJsonKey: secretData},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			By("creating a pod referencing the private image with imagePullSecrets")
+			_, err = c.Pods(ns).Create(&api.Pod{
+				ObjectMeta: api.ObjectMeta{Name: podName},
+				Spec: api.PodSpec{
+					Containers: []api.Container{
+						{
+							Name:  "marker",
+							Image: image,
+						},
+					},
+					ImagePullSecrets: []api.LocalObjectReference{{Name: secretName}},
+					RestartPolicy:    api.RestartPolicyNever,
+				},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			defer framework.RunKubectlOrDie("delete", "pod", podName, nsFlag)
+
+			By("waiting for the pod to reach Running")
+			framework.KubectlWaitOrDie(c, framework.ResourceRef{Kind: "Pod", Namespace: ns, Name: podName}, framework.Ready(), framework.PodStartTimeout)
+
+			By("verifying the pod's logs contain the expected marker")
+			_, err = framework.LookForStringInLog(ns, podName, "marker", marker, framework.PodStartTimeout)
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+
 	framework.KubeDescribe("Proxy server", func() {
 		// TODO: test proxy options (static, prefix, etc)
 		It("should support proxy with --port 0 [Conformance]", func() {
@@ -1114,6 +1645,76 @@ var _ = framework.KubeDescribe("Kubectl client", func() {
 				framework.Failf("Failed get of /api at %s: %v", path, err)
 			}
 		})
+
+		It("should serve static files under --www with --www-prefix and --api-prefix", func() {
+			By("writing a file into a tempdir to serve as --www")
+			wwwDir, err := ioutil.TempDir("", "kubectl-proxy-www")
+			if err != nil {
+				framework.Failf("Failed to create temporary directory: %v", err)
+			}
+			defer os.RemoveAll(wwwDir)
+			const fileContents = "hello from --www"
+			if err := ioutil.WriteFile(filepath.Join(wwwDir, "hello.txt"), []byte(fileContents), 0644); err != nil {
+				framework.Failf("Failed to write test file: %v", err)
+			}
+
+			By("starting the proxy with --www, --www-prefix, and --api-prefix")
+			port, cmd, err := startProxyServerWithOptions(wwwDir, "/static/", "/custom-api/", nil)
+			if cmd != nil {
+				defer framework.TryKill(cmd)
+			}
+			if err != nil {
+				framework.Failf("Failed to start proxy server: %v", err)
+			}
+
+			By("fetching the file through the static prefix")
+			body, err := curl(fmt.Sprintf("http://localhost:%d/static/hello.txt", port))
+			if err != nil {
+				framework.Failf("Failed to curl static file: %v", err)
+			}
+			if body != fileContents {
+				framework.Failf("Expected static file contents %q, got %q", fileContents, body)
+			}
+
+			By("verifying the API still works under the custom --api-prefix")
+			apiVersions, err := getAPIVersions(fmt.Sprintf("http://localhost:%d/custom-api/api/", port))
+			if err != nil {
+				framework.Failf("Expected at least one supported apiversion, got error %v", err)
+			}
+			if len(apiVersions.Versions) < 1 {
+				framework.Failf("Expected at least one supported apiversion, got %v", apiVersions)
+			}
+
+			By("verifying requests outside both prefixes 404")
+			resp, err := http.Get(fmt.Sprintf("http://localhost:%d/nowhere", port))
+			if err != nil {
+				framework.Failf("Failed to curl unmapped path: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusNotFound {
+				framework.Failf("Expected 404 for unmapped path, got %d", resp.StatusCode)
+			}
+		})
+
+		It("should refuse requests matching --reject-paths with 403", func() {
+			By("starting the proxy with --reject-paths")
+			port, cmd, err := startProxyServerWithOptions("", "", "", []string{"^/api/v1/secrets"})
+			if cmd != nil {
+				defer framework.TryKill(cmd)
+			}
+			if err != nil {
+				framework.Failf("Failed to start proxy server: %v", err)
+			}
+
+			resp, err := http.Get(fmt.Sprintf("http://localhost:%d/api/v1/secrets", port))
+			if err != nil {
+				framework.Failf("Failed to curl rejected path: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusForbidden {
+				framework.Failf("Expected 403 for rejected path, got %d", resp.StatusCode)
+			}
+		})
 	})
 
 	framework.KubeDescribe("Kubectl taint", func() {
@@ -1205,6 +1806,46 @@ func startProxyServer() (int, *exec.Cmd, error) {
 	return -1, cmd, fmt.Errorf("Failed to parse port from proxy stdout: %s", output)
 }
 
+// startProxyServerWithOptions is like startProxyServer but additionally
+// passes --www/--www-prefix/--api-prefix/--reject-paths. Empty strings are
+// omitted so callers can exercise a subset of the options.
+func startProxyServerWithOptions(wwwDir, wwwPrefix, apiPrefix string, rejectPaths []string) (int, *exec.Cmd, error) {
+	args := []string{"proxy", "-p", "0"}
+	if wwwDir != "" {
+		args = append(args, fmt.Sprintf("--www=%s", wwwDir))
+	}
+	if wwwPrefix != "" {
+		args = append(args, fmt.Sprintf("--www-prefix=%s", wwwPrefix))
+	}
+	if apiPrefix != "" {
+		args = append(args, fmt.Sprintf("--api-prefix=%s", apiPrefix))
+	}
+	for _, p := range rejectPaths {
+		args = append(args, fmt.Sprintf("--reject-paths=%s", p))
+	}
+
+	cmd := framework.KubectlCmd(args...)
+	stdout, stderr, err := framework.StartCmdAndStreamOutput(cmd)
+	if err != nil {
+		return -1, nil, err
+	}
+	defer stdout.Close()
+	defer stderr.Close()
+	buf := make([]byte, 128)
+	var n int
+	if n, err = stdout.Read(buf); err != nil {
+		return -1, cmd, fmt.Errorf("Failed to read from kubectl proxy stdout: %v", err)
+	}
+	output := string(buf[:n])
+	match := proxyRegexp.FindStringSubmatch(output)
+	if len(match) == 2 {
+		if port, err := strconv.Atoi(match[1]); err == nil {
+			return port, cmd, nil
+		}
+	}
+	return -1, cmd, fmt.Errorf("Failed to parse port from proxy stdout: %s", output)
+}
+
 func curlUnix(url string, path string) (string, error) {
 	dial := func(proto, addr string) (net.Conn, error) {
 		return net.Dial("unix", path)
@@ -1233,33 +1874,145 @@ func curl(url string) (string, error) {
 	return curlTransport(url, utilnet.SetTransportDefaults(&http.Transport{}))
 }
 
+// publishValidationEvent records a validation-kind Event on the default
+// event bus, the structured replacement for the framework.Logf calls
+// validators used to make. Consumers tail /events?filter=kind=validation
+// instead of scraping log text for this information.
+func publishValidationEvent(resource, namespace, message string) {
+	framework.PublishEvent(framework.Event{
+		Test:      CurrentGinkgoTestDescription().FullTestText,
+		Kind:      "validation",
+		Resource:  resource,
+		Namespace: namespace,
+		Message:   message,
+	})
+}
+
 func validateGuestbookApp(c *client.Client, ns string) {
-	framework.Logf("Waiting for frontend to serve content.")
+	publishValidationEvent("guestbook", ns, "waiting for frontend to serve content")
 	if !waitForGuestbookResponse(c, "get", "", `{"data": ""}`, guestbookStartupTimeout, ns) {
 		framework.Failf("Frontend service did not start serving content in %v seconds.", guestbookStartupTimeout.Seconds())
 	}
 
-	framework.Logf("Trying to add a new entry to the guestbook.")
+	publishValidationEvent("guestbook", ns, "adding a new entry to the guestbook")
 	if !waitForGuestbookResponse(c, "set", "TestEntry", `{"message": "Updated"}`, guestbookResponseTimeout, ns) {
 		framework.Failf("Cannot added new entry in %v seconds.", guestbookResponseTimeout.Seconds())
 	}
 
-	framework.Logf("Verifying that added entry can be retrieved.")
+	publishValidationEvent("guestbook", ns, "verifying that added entry can be retrieved")
 	if !waitForGuestbookResponse(c, "get", "", `{"data": "TestEntry"}`, guestbookResponseTimeout, ns) {
 		framework.Failf("Entry to guestbook wasn't correctly added in %v seconds.", guestbookResponseTimeout.Seconds())
 	}
 }
 
+// errGuestbookNotReady marks a guestbook response that doesn't match what's
+// expected yet (the app hasn't started, or hasn't processed a write yet) as
+// distinct from a terminal client error, so retryGuestbook keeps polling
+// instead of giving up.
+var errGuestbookNotReady = errors.New("guestbook: response did not match expected value")
+
+// retryGuestbook wraps calls through the guestbook proxy in a
+// framework.RetryClient so a flaky connection reset or 5xx is retried with
+// backoff instead of being treated the same as a permanent failure, and a
+// genuinely broken app (a terminal 4xx) surfaces immediately rather than
+// only after timeout expires.
+var retryGuestbook = func() *framework.RetryClient {
+	c := framework.NewRetryClient()
+	c.ShouldRetry = func(err error) bool {
+		return err == errGuestbookNotReady || framework.IsRetryableError(err)
+	}
+	return c
+}()
+
 // Returns whether received expected response from guestbook on time.
 func waitForGuestbookResponse(c *client.Client, cmd, arg, expectedResponse string, timeout time.Duration, ns string) bool {
-	for start := time.Now(); time.Since(start) < timeout; time.Sleep(5 * time.Second) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	result, err := retryGuestbook.Do(ctx, func() ([]byte, error) {
 		res, err := makeRequestToGuestbook(c, cmd, arg, ns)
-		if err == nil && res == expectedResponse {
-			return true
+		if err != nil {
+			return nil, err
 		}
-		framework.Logf("Failed to get response from guestbook. err: %v, response: %s", err, res)
+		if res != expectedResponse {
+			framework.Logf("Unexpected response from guestbook: %s", res)
+			return nil, errGuestbookNotReady
+		}
+		return []byte(res), nil
+	})
+	return err == nil && string(result) == expectedResponse
+}
+
+// dockerCompatShimServiceName is the headless Service mountDockerCompatShim
+// creates to front the in-process dockercompat handler.
+const dockerCompatShimServiceName = "dockercompat-shim"
+
+// mountDockerCompatShim points a headless Service -- and hand-built
+// Endpoints, since nothing selects it -- at shim's listener address, so the
+// dockercompat handler it serves can be reached through the apiserver's real
+// services/proxy subresource instead of being queried directly. This only
+// works where the apiserver can reach the test process's loopback address,
+// i.e. the "local" provider.
+func mountDockerCompatShim(c *client.Client, ns string, shim *httptest.Server) string {
+	host, portStr, err := net.SplitHostPort(shim.Listener.Addr().String())
+	if err != nil {
+		framework.Failf("Error parsing dockercompat shim address %q: %v", shim.Listener.Addr(), err)
 	}
-	return false
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		framework.Failf("Error parsing dockercompat shim port %q: %v", portStr, err)
+	}
+
+	svc := &api.Service{
+		ObjectMeta: api.ObjectMeta{Name: dockerCompatShimServiceName},
+		Spec: api.ServiceSpec{
+			ClusterIP: api.ClusterIPNone,
+			Ports:     []api.ServicePort{{Port: int32(port)}},
+		},
+	}
+	if _, err := c.Services(ns).Create(svc); err != nil {
+		framework.Failf("Error creating dockercompat shim service: %v", err)
+	}
+
+	endpoints := &api.Endpoints{
+		ObjectMeta: api.ObjectMeta{Name: dockerCompatShimServiceName},
+		Subsets: []api.EndpointSubset{{
+			Addresses: []api.EndpointAddress{{IP: host}},
+			Ports:     []api.EndpointPort{{Port: int32(port)}},
+		}},
+	}
+	if _, err := c.Endpoints(ns).Create(endpoints); err != nil {
+		framework.Failf("Error creating dockercompat shim endpoints: %v", err)
+	}
+	return dockerCompatShimServiceName
+}
+
+// dockerCompatContainerNames hits a dockercompat shim Service's
+// /containers/json endpoint through the apiserver's services/proxy
+// subresource and returns the container names it reports, the same call a
+// real Docker client makes to list running containers.
+func dockerCompatContainerNames(c *client.Client, ns, svcName string) []string {
+	proxyRequest, err := framework.GetServicesProxyRequest(c, c.Get())
+	if err != nil {
+		return nil
+	}
+	body, err := proxyRequest.Namespace(ns).
+		Name(svcName).
+		Suffix("v1.24", "containers", "json").
+		Do().
+		Raw()
+	if err != nil {
+		return nil
+	}
+	var items []dockercompat.ContainerListItem
+	if err := json.Unmarshal(body, &items); err != nil {
+		return nil
+	}
+	var names []string
+	for _, item := range items {
+		names = append(names, item.Names...)
+	}
+	return names
 }
 
 func makeRequestToGuestbook(c *client.Client, cmd, value string, ns string) (string, error) {
@@ -1307,6 +2060,23 @@ func readReplicationControllerFromString(contents string) *api.ReplicationContro
 	return &rc
 }
 
+// guardedRCClient adapts client.Client's ReplicationControllers(ns) resource
+// interface to framework.GuardedUpdateClient, the fixed Get()/Update(obj)
+// shape GuardedUpdate needs regardless of which resource it's guarding.
+type guardedRCClient struct {
+	c    *client.Client
+	ns   string
+	name string
+}
+
+func (g guardedRCClient) Get() (runtime.Object, error) {
+	return g.c.ReplicationControllers(g.ns).Get(g.name)
+}
+
+func (g guardedRCClient) Update(obj runtime.Object) (runtime.Object, error) {
+	return g.c.ReplicationControllers(g.ns).Update(obj.(*api.ReplicationController))
+}
+
 func modifyReplicationControllerConfiguration(contents string) io.Reader {
 	rc := readReplicationControllerFromString(contents)
 	rc.Labels[applyTestLabel] = "ADDED"
@@ -1320,18 +2090,35 @@ func modifyReplicationControllerConfiguration(contents string) io.Reader {
 	return bytes.NewReader(data)
 }
 
+// errReplicationControllersNotYetListed marks an empty list result as
+// something forEachReplicationController should keep retrying, rather than
+// a terminal list error.
+var errReplicationControllersNotYetListed = errors.New("no replication controllers found yet")
+
 func forEachReplicationController(c *client.Client, ns, selectorKey, selectorValue string, fn func(api.ReplicationController)) {
+	ctx, cancel := context.WithTimeout(context.Background(), framework.PodListTimeout)
+	defer cancel()
+
+	retry := framework.NewRetryClient()
+	retry.ShouldRetry = func(err error) bool {
+		return err == errReplicationControllersNotYetListed || framework.IsRetryableError(err)
+	}
+
 	var rcs *api.ReplicationControllerList
-	var err error
-	for t := time.Now(); time.Since(t) < framework.PodListTimeout; time.Sleep(framework.Poll) {
+	_, err := retry.Do(ctx, func() ([]byte, error) {
 		label := labels.SelectorFromSet(labels.Set(map[string]string{selectorKey: selectorValue}))
 		options := api.ListOptions{LabelSelector: label}
-		rcs, err = c.ReplicationControllers(ns).List(options)
-		Expect(err).NotTo(HaveOccurred())
-		if len(rcs.Items) > 0 {
-			break
+		list, err := c.ReplicationControllers(ns).List(options)
+		if err != nil {
+			return nil, err
 		}
-	}
+		if len(list.Items) == 0 {
+			return nil, errReplicationControllersNotYetListed
+		}
+		rcs = list
+		return nil, nil
+	})
+	Expect(err).NotTo(HaveOccurred())
 
 	if rcs == nil || len(rcs.Items) == 0 {
 		framework.Failf("No replication controllers found")
@@ -1343,6 +2130,7 @@ func forEachReplicationController(c *client.Client, ns, selectorKey, selectorVal
 }
 
 func validateReplicationControllerConfiguration(rc api.ReplicationController) {
+	publishValidationEvent("replicationcontrollers", rc.Namespace, "checking applied configuration on "+rc.Name)
 	if rc.Name == "redis-master" {
 		if _, ok := rc.Annotations[annotations.LastAppliedConfigAnnotation]; !ok {
 			framework.Failf("Annotation not found in modified configuration:\n%v\n", rc)
@@ -1361,7 +2149,7 @@ func getUDData(jpgExpected string, ns string) func(*client.Client, string) error
 
 	// getUDData validates data.json in the update-demo (returns nil if data is ok).
 	return func(c *client.Client, podID string) error {
-		framework.Logf("validating pod %s", podID)
+		publishValidationEvent("pods", ns, "validating pod "+podID)
 		subResourceProxyAvailable, err := framework.ServerVersionGTE(framework.SubResourcePodProxyVersion, c)
 		if err != nil {
 			return err
@@ -1389,12 +2177,12 @@ func getUDData(jpgExpected string, ns string) func(*client.Client, string) error
 		if err != nil {
 			return err
 		}
-		framework.Logf("got data: %s", body)
+		publishValidationEvent("pods", ns, fmt.Sprintf("got data for pod %s: %s", podID, body))
 		var data updateDemoData
 		if err := json.Unmarshal(body, &data); err != nil {
 			return err
 		}
-		framework.Logf("Unmarshalled json jpg/img => %s , expecting %s .", data, jpgExpected)
+		publishValidationEvent("pods", ns, fmt.Sprintf("unmarshalled json jpg/img => %s, expecting %s", data, jpgExpected))
 		if strings.Contains(data.Image, jpgExpected) {
 			return nil
 		} else {
@@ -1422,75 +2210,121 @@ func newBlockingReader(s string) (io.Reader, io.Closer, error) {
 	return r, w, nil
 }
 
-// newStreamingUpload creates a new http.Request that will stream POST
-// a file to a URI.
-func newStreamingUpload(filePath string) (*io.PipeReader, *multipart.Writer, error) {
+// uploadFileResumable streams filePath to url as a chunked, resumable
+// multipart upload via framework.StreamingUploader. It replaces the old
+// newStreamingUpload/streamingUpload pair, a single-shot pipe-and-goroutine
+// copy with no retry or progress reporting that flaked on large kubectl
+// binary uploads in CI.
+func uploadFileResumable(url, filePath string) error {
 	file, err := os.Open(filePath)
 	if err != nil {
-		return nil, nil, err
+		return err
 	}
+	defer file.Close()
 
-	r, w := io.Pipe()
-
-	postBodyWriter := multipart.NewWriter(w)
+	stat, err := file.Stat()
+	if err != nil {
+		return err
+	}
 
-	go streamingUpload(file, filepath.Base(filePath), postBodyWriter, w)
-	return r, postBodyWriter, err
+	uploader := framework.NewStreamingUploader(url, "file", filepath.Base(filePath))
+	return uploader.Upload(context.Background(), file, stat.Size())
 }
 
-// streamingUpload streams a file via a pipe through a multipart.Writer.
-// Generally one should use newStreamingUpload instead of calling this directly.
-func streamingUpload(file *os.File, fileName string, postBodyWriter *multipart.Writer, w *io.PipeWriter) {
-	defer GinkgoRecover()
-	defer file.Close()
-	defer w.Close()
+// artifactResolver is the process-wide content-addressable cache used by
+// findBinary, shared across parallel Ginkgo nodes via the cache dir's
+// flock'd lockfile rather than one resolver per node racing the filesystem.
+// artifactSourceHash is the git commit of RepoRoot at process start, used as
+// the sourceHash so a rebuild against a new commit gets a fresh cache entry
+// instead of Resolve forever returning whatever was resolved first. Both are
+// constructed lazily because TestContext.RepoRoot isn't populated until
+// flags are parsed.
+var (
+	artifactResolver     *framework.ArtifactResolver
+	artifactSourceHash   string
+	artifactResolverOnce sync.Once
+)
 
-	// Set up the form file
-	fileWriter, err := postBodyWriter.CreateFormFile("file", fileName)
+// findBinary resolves the specified binary through artifactResolver and
+// materializes it to a local path. Platform should be specified as
+// '<os>/<arch>'. For example: 'linux/amd64'.
+func findBinary(binName string, platform string) (string, error) {
+	artifactResolverOnce.Do(func() {
+		artifactResolver = framework.NewArtifactResolver(path.Join(framework.TestContext.RepoRoot, "_output/cache"), nil)
+		artifactSourceHash = repoSourceHash(framework.TestContext.RepoRoot)
+	})
+	handle, err := artifactResolver.Resolve(binName, platform, artifactSourceHash)
 	if err != nil {
-		framework.Failf("Unable to to write file at %s to buffer. Error: %s", fileName, err)
-	}
-
-	// Copy kubectl binary into the file writer
-	if _, err := io.Copy(fileWriter, file); err != nil {
-		framework.Failf("Unable to to copy file at %s into the file writer. Error: %s", fileName, err)
+		return "", err
 	}
+	return handle.Materialize()
+}
 
-	// Nothing more should be written to this instance of the postBodyWriter
-	if err := postBodyWriter.Close(); err != nil {
-		framework.Failf("Unable to close the writer for file upload. Error: %s", err)
+// repoSourceHash returns the git commit of repoRoot, or "" if it can't be
+// determined (e.g. a source tree exported without a .git directory), in
+// which case Resolve falls back to its existing mtime-scan cache-miss
+// behavior every time rather than ever appearing stale.
+func repoSourceHash(repoRoot string) string {
+	out, err := exec.Command("git", "-C", repoRoot, "rev-parse", "HEAD").Output()
+	if err != nil {
+		return ""
 	}
+	return strings.TrimSpace(string(out))
 }
 
-var binPrefixes = []string{
-	"_output/dockerized/bin",
-	"_output/local/bin",
-	"platforms",
+// apiServerHostname returns the bare host (no scheme, no port, no path)
+// that framework.TestContext.Host resolves to, for comparing against what a
+// proxy logged it accepted a CONNECT to. Uses net/url instead of the
+// cutset-based strings.TrimRight(strings.TrimLeft(...), "/api") this used to
+// do, which trimmed any trailing character found in the set {a, p, i, /}
+// rather than the literal suffix "/api" -- e.g. it would eat a trailing "a"
+// from a hostname like "kubernetes.io".
+func apiServerHostname() string {
+	u, err := url.Parse(framework.TestContext.Host)
+	if err != nil {
+		framework.Failf("Could not parse --host %q: %v", framework.TestContext.Host, err)
+	}
+	return u.Hostname()
 }
 
-// findBinary searches through likely paths to find the specified binary.  It
-// takes the one that has been built most recently.  Platform should be
-// specified as '<os>/<arch>'.  For example: 'linux/amd64'.
-func findBinary(binName string, platform string) (string, error) {
-	var binTime time.Time
-	var binPath string
+// startKubectlProxy runs "kubectl proxy" as a real subprocess with env as
+// its entire environment and returns the local address it
+// picked to listen on, parsed from RunProxy's "Starting to serve on ..."
+// line, plus a func to stop it.
+//
+// This is the only way to actually exercise ALL_PROXY/HTTPS_PROXY/NO_PROXY
+// handling end to end in this snapshot: pkg/client/unversioned.TransportFor
+// is wired into pkg/kubectl/cmd.RunProxy's apiserver-facing leg (see
+// proxy.go), not into the REST client construction every other kubectl
+// subcommand (exec, logs, ...) goes through, which isn't part of this
+// snapshot at all.
+func startKubectlProxy(env []string) (addr string, stop func(), err error) {
+	cmd := framework.KubectlCmd("proxy", "--address=127.0.0.1", "--port=0")
+	cmd.Env = env
 
-	for _, pre := range binPrefixes {
-		tryPath := path.Join(framework.TestContext.RepoRoot, pre, platform, binName)
-		fi, err := os.Stat(tryPath)
-		if err != nil {
-			continue
-		}
-		if fi.ModTime().After(binTime) {
-			binPath = tryPath
-			binTime = fi.ModTime()
-		}
+	stdout, stderr, err := framework.StartCmdAndStreamOutput(cmd)
+	if err != nil {
+		return "", nil, err
 	}
 
-	if len(binPath) > 0 {
-		return binPath, nil
+	scanner := bufio.NewScanner(stdout)
+	var addrLine string
+	if scanner.Scan() {
+		addrLine = scanner.Text()
+	}
+	matches := regexp.MustCompile(`Starting to serve on (\S+)`).FindStringSubmatch(addrLine)
+	if len(matches) != 2 {
+		cmd.Process.Kill()
+		return "", nil, fmt.Errorf("could not parse kubectl proxy's listen address from %q", addrLine)
 	}
-	return binPath, fmt.Errorf("Could not find %v for %v", binName, platform)
+
+	go io.Copy(ioutil.Discard, stdout)
+	go io.Copy(ioutil.Discard, stderr)
+
+	return matches[1], func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	}, nil
 }
 
 func startLocalProxy() (srv *httptest.Server, logs *bytes.Buffer) {
@@ -1500,3 +2334,40 @@ func startLocalProxy() (srv *httptest.Server, logs *bytes.Buffer) {
 	p.Logger = log.New(logs, "", 0)
 	return httptest.NewServer(p), logs
 }
+
+// startLocalProxyWithAuth is like startLocalProxy but rejects CONNECT
+// requests that don't present the given Basic auth credentials via the
+// Proxy-Authorization header.
+func startLocalProxyWithAuth(user, pass string) (srv *httptest.Server, logs *bytes.Buffer) {
+	logs = &bytes.Buffer{}
+	p := goproxy.NewProxyHttpServer()
+	p.Verbose = true
+	p.Logger = log.New(logs, "", 0)
+	auth.ProxyBasic(p, "e2e-proxy", func(u, pwd string) bool {
+		return u == user && pwd == pass
+	})
+	return httptest.NewServer(p), logs
+}
+
+// startLocalSocks5Proxy starts a SOCKS5 proxy on a local port and returns its
+// address, a counter of the number of connections it has proxied, and a stop
+// function. It backs the ALL_PROXY=socks5://... e2e coverage for kubectl.
+func startLocalSocks5Proxy() (addr string, connCount func() int, stop func()) {
+	var count int32
+	conf := &socks5.Config{
+		Dial: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			atomic.AddInt32(&count, 1)
+			return net.Dial(network, addr)
+		},
+	}
+	server, err := socks5.New(conf)
+	if err != nil {
+		framework.Failf("Failed to create SOCKS5 proxy: %v", err)
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		framework.Failf("Failed to listen for SOCKS5 proxy: %v", err)
+	}
+	go server.Serve(listener)
+	return listener.Addr().String(), func() int { return int(atomic.LoadInt32(&count)) }, func() { listener.Close() }
+}